@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/client/services"
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+// errUsage is returned by commands that are called with wrong arguments
+var errUsage = errors.New("wrong arguments")
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error
+}
+
+var commands = map[string]command{
+	"upload": {
+		usage: "[-conflict reject|overwrite|rename] <file>...",
+		run:   runUpload,
+	},
+	"upload-dir": {
+		usage: "[-ext .jpg,.png] [-pattern glob] [-workers n] [-skip-existing] [-conflict reject|overwrite|rename] <dir>",
+		run:   runUploadDir,
+	},
+	"download": {
+		usage: "[-name] [-variant name] <id>",
+		run:   runDownload,
+	},
+	"list": {
+		usage: "[-all] [-all-owners] [-pattern pattern] [-type type] [-sort name|-name|created|-created] [-page-size n]",
+		run:   runList,
+	},
+	"info": {
+		usage: "<id>",
+		run:   runInfo,
+	},
+	"server-info": {
+		usage: "",
+		run:   runServerInfo,
+	},
+	"delete": {
+		usage: "<id>",
+		run:   runDelete,
+	},
+	"restore": {
+		usage: "<id>",
+		run:   runRestore,
+	},
+	"rename": {
+		usage: "<id> <new name>",
+		run:   runRename,
+	},
+}
+
+var conflictPolicies = map[string]protos.ConflictPolicy{
+	"reject":    protos.ConflictPolicy_REJECT,
+	"overwrite": protos.ConflictPolicy_OVERWRITE,
+	"rename":    protos.ConflictPolicy_AUTO_RENAME,
+}
+
+var sortOrders = map[string]protos.GetImageInfoListRequest_SortOrder{
+	"name":     protos.GetImageInfoListRequest_NAME_ASC,
+	"-name":    protos.GetImageInfoListRequest_NAME_DESC,
+	"created":  protos.GetImageInfoListRequest_CREATED_AT_ASC,
+	"-created": protos.GetImageInfoListRequest_CREATED_AT_DESC,
+}
+
+func runUpload(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("upload")
+	conflict := flags.String("conflict", "reject", "what to do when the server already has an image with the name")
+	if err := parseFlags(flags, args, 1, -1); err != nil {
+		return err
+	}
+	conflictPolicy, ok := conflictPolicies[*conflict]
+	if !ok {
+		return fmt.Errorf("%w: unknown conflict policy %q", errUsage, *conflict)
+	}
+
+	// every file is tried, the command fails if any of them does
+	results := make([]*protos.UploadImageResponse, 0)
+	var failed int
+	for _, imagePath := range flags.Args() {
+		res, err := imageClient.UploadFile(ctx, imagePath, conflictPolicy)
+		if err != nil {
+			log.Printf("%s: %v", imagePath, err)
+			failed++
+			continue
+		}
+		log.Printf("image %s uploaded as %s with id: %s, size: %d, deduplicated: %t", imagePath, res.GetImageName(), res.GetId(), res.GetSize(), res.GetDeduplicated())
+		results = append(results, res)
+	}
+
+	rows := make([][]string, 0, len(results))
+	for _, res := range results {
+		rows = append(rows, []string{res.GetId(), res.GetImageName(), fmt.Sprint(res.GetSize()), fmt.Sprint(res.GetDeduplicated())})
+	}
+	err := out.print(results, []string{"ID", "NAME", "SIZE", "DEDUPLICATED"}, rows)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("cannot upload %d of %d images", failed, flags.NArg())
+	}
+	return nil
+}
+
+func runUploadDir(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("upload-dir")
+	extensions := flags.String("ext", "", "comma separated extensions of the files to upload")
+	pattern := flags.String("pattern", "", "glob of the files to upload, matched against the path if it contains a slash")
+	workers := flags.Int("workers", 0, "number of concurrent uploads, the server limit if zero")
+	skipExisting := flags.Bool("skip-existing", false, "skip files that are already on the server")
+	conflict := flags.String("conflict", "reject", "what to do when the server already has an image with the name")
+	if err := parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	conflictPolicy, ok := conflictPolicies[*conflict]
+	if !ok {
+		return fmt.Errorf("%w: unknown conflict policy %q", errUsage, *conflict)
+	}
+
+	opts := services.DirectoryUploadOptions{
+		Pattern:        *pattern,
+		Workers:        *workers,
+		SkipExisting:   *skipExisting,
+		ConflictPolicy: conflictPolicy,
+	}
+	if *extensions != "" {
+		opts.Extensions = strings.Split(*extensions, ",")
+	}
+
+	report, err := imageClient.UploadDirectory(ctx, flags.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	type fileResult struct {
+		Path   string `json:"path"`
+		Status string `json:"status"`
+		ID     string `json:"id,omitempty"`
+		Name   string `json:"image_name,omitempty"`
+		Size   int64  `json:"size"`
+		Error  string `json:"error,omitempty"`
+	}
+	results := make([]fileResult, 0, len(report.Results))
+	rows := make([][]string, 0, len(report.Results))
+	for _, result := range report.Results {
+		fileResult := fileResult{
+			Path:   result.Path,
+			Status: "uploaded",
+			ID:     result.Response.GetId(),
+			Name:   result.Response.GetImageName(),
+			Size:   result.Size,
+		}
+		switch {
+		case result.Err != nil:
+			fileResult.Status = "failed"
+			fileResult.Error = result.Err.Error()
+		case result.Skipped:
+			fileResult.Status = "skipped"
+		}
+		results = append(results, fileResult)
+		rows = append(rows, []string{fileResult.Path, fileResult.Status, fileResult.ID, fileResult.Name, fmt.Sprint(fileResult.Size), fileResult.Error})
+	}
+	err = out.print(results, []string{"PATH", "STATUS", "ID", "NAME", "SIZE", "ERROR"}, rows)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("uploaded %d, skipped %d, failed %d of %d files, sent %d bytes in %v",
+		report.Uploaded, report.Skipped, report.Failed, len(report.Results), report.BytesSent, report.Duration.Round(time.Millisecond))
+	if report.Failed > 0 {
+		return fmt.Errorf("cannot upload %d of %d files", report.Failed, len(report.Results))
+	}
+	return nil
+}
+
+func runDownload(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("download")
+	byName := flags.Bool("name", false, "the argument is the image name instead of the id")
+	variant := flags.String("variant", "", "download a resized variant of the image")
+	if err := parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+	if *byName && *variant != "" {
+		return fmt.Errorf("%w: variants are downloaded by id", errUsage)
+	}
+
+	targetPath := out.path
+	if targetPath == "" {
+		targetPath = "."
+	}
+	// a directory as the target keeps the name the image has on the server
+	targetDir := ""
+	if fileInfo, err := os.Stat(targetPath); err == nil && fileInfo.IsDir() {
+		targetDir = targetPath
+	}
+
+	folder := targetDir
+	if folder == "" {
+		folder = filepath.Dir(targetPath)
+	}
+	file, err := os.CreateTemp(folder, ".download-*")
+	if err != nil {
+		return fmt.Errorf("cannot create image file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	var info *protos.ImageInfo
+	switch {
+	case *byName:
+		info, err = imageClient.DownloadImageByName(ctx, flags.Arg(0), writer)
+	case *variant != "":
+		info, err = imageClient.DownloadImageVariant(ctx, flags.Arg(0), *variant, writer)
+	default:
+		info, err = imageClient.DownloadImage(ctx, flags.Arg(0), writer)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("cannot write image file: %w", err)
+	}
+	err = file.Chmod(0644)
+	if err != nil {
+		return fmt.Errorf("cannot change image file mode: %w", err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("cannot close image file: %w", err)
+	}
+
+	if targetDir != "" {
+		targetPath = filepath.Join(targetDir, filepath.Base(info.GetImageName()))
+	}
+	err = os.Rename(file.Name(), targetPath)
+	if err != nil {
+		return fmt.Errorf("cannot move image file into place: %w", err)
+	}
+
+	log.Printf("image %s downloaded to %s", info.GetImageName(), targetPath)
+	return nil
+}
+
+func runList(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("list")
+	all := flags.Bool("all", false, "include images in the trash")
+	allOwners := flags.Bool("all-owners", false, "list the images of every owner, admins only")
+	pattern := flags.String("pattern", "", "name prefix, or a glob if it contains any of *?[")
+	imageType := flags.String("type", "", "image type, such as jpg")
+	sortBy := flags.String("sort", "name", "sort order")
+	pageSize := flags.Int("page-size", 0, "number of images fetched per call, the server default if zero")
+	if err := parseFlags(flags, args, 0, 0); err != nil {
+		return err
+	}
+	sortOrder, ok := sortOrders[*sortBy]
+	if !ok {
+		return fmt.Errorf("%w: unknown sort order %q", errUsage, *sortBy)
+	}
+
+	imageInfos, err := imageClient.GetImageInfoList(ctx, &protos.GetImageInfoListRequest{
+		IncludeDeleted: *all,
+		AllOwners:      *allOwners,
+		NamePattern:    *pattern,
+		ImageType:      *imageType,
+		SortOrder:      sortOrder,
+		PageSize:       int32(*pageSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(imageInfos))
+	for _, info := range imageInfos {
+		rows = append(rows, imageInfoRow(info))
+	}
+	return out.print(imageInfos, imageInfoHeader, rows)
+}
+
+func runInfo(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("info")
+	if err := parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	info, err := imageClient.GetImageInfo(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return out.print(info, imageInfoHeader, [][]string{imageInfoRow(info)})
+}
+
+// runServerInfo prints the request queues of the server, the JSON output also has its limits
+func runServerInfo(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("server-info")
+	if err := parseFlags(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	serverInfo, err := imageClient.GetServerInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(serverInfo.GetAdmission()))
+	for _, stats := range serverInfo.GetAdmission() {
+		averageWait := time.Duration(0)
+		if requests := stats.GetAdmitted() + stats.GetRejected(); requests > 0 {
+			averageWait = time.Duration(stats.GetTotalWaitMs()/requests) * time.Millisecond
+		}
+		rows = append(rows, []string{
+			stats.GetName(),
+			strconv.FormatInt(stats.GetLimit(), 10),
+			strconv.FormatInt(stats.GetRunning(), 10),
+			strconv.FormatInt(stats.GetWaiting(), 10),
+			strconv.FormatInt(stats.GetAdmitted(), 10),
+			strconv.FormatInt(stats.GetRejected(), 10),
+			averageWait.String(),
+		})
+	}
+	return out.print(serverInfo, []string{"QUEUE", "LIMIT", "RUNNING", "WAITING", "ADMITTED", "REJECTED", "AVG WAIT"}, rows)
+}
+
+func runDelete(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("delete")
+	if err := parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	err := imageClient.DeleteImage(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	log.Printf("image with id %s moved to the trash", flags.Arg(0))
+	return nil
+}
+
+func runRestore(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("restore")
+	if err := parseFlags(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	err := imageClient.RestoreImage(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	log.Printf("image with id %s restored from the trash", flags.Arg(0))
+	return nil
+}
+
+func runRename(ctx context.Context, imageClient *services.ImageClient, out *printer, args []string) error {
+	flags := newFlagSet("rename")
+	if err := parseFlags(flags, args, 2, 2); err != nil {
+		return err
+	}
+
+	res, err := imageClient.RenameImage(ctx, flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	return out.print(res, []string{"ID", "NAME"}, [][]string{{res.GetId(), res.GetImageName()}})
+}
+
+var imageInfoHeader = []string{"ID", "NAME", "OWNER", "MIME TYPE", "SIZE", "CREATED", "UPDATED", "DELETED"}
+
+func imageInfoRow(info *protos.ImageFullInfo) []string {
+	size := ""
+	if info.GetWidth() > 0 {
+		size = fmt.Sprintf("%dx%d", info.GetWidth(), info.GetHeight())
+	}
+	return []string{
+		info.GetId(),
+		info.GetImageName(),
+		info.GetOwner(),
+		info.GetMimeType(),
+		size,
+		info.GetCreatedAt(),
+		info.GetUpdatedAt(),
+		info.GetDeletedAt(),
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// parseFlags parses the command flags and checks the number of the remaining arguments,
+// a negative max means there is no upper limit
+func parseFlags(flags *flag.FlagSet, args []string, min int, max int) error {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: flags of %s:\n%s", errUsage, flags.Name(), flagDefaults(flags))
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() < min || (max >= 0 && flags.NArg() > max) {
+		return fmt.Errorf("%w: got %d arguments", errUsage, flags.NArg())
+	}
+	return nil
+}
+
+func flagDefaults(flags *flag.FlagSet) string {
+	var defaults strings.Builder
+	flags.SetOutput(&defaults)
+	flags.PrintDefaults()
+	flags.SetOutput(io.Discard)
+	return defaults.String()
+}
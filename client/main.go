@@ -1,27 +1,158 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/navruz-rakhimov/tages-project/client/services"
+	"github.com/navruz-rakhimov/tages-project/tracing"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
-	address = "localhost:5001"
+	exitFailure = 1
+	exitUsage   = 2
+
+	// tracingFlushTimeout bounds how long the spans that are left may take to export on exit
+	tracingFlushTimeout = 5 * time.Second
 )
 
+var tracer = otel.Tracer("github.com/navruz-rakhimov/tages-project/client")
+
 func main() {
-	log.Printf("dial server %s", address)
+	address := flag.String("addr", "localhost:5001", "address of the image server")
+	timeout := flag.Duration("timeout", 5*time.Minute, "timeout of the whole command")
+	format := flag.String("format", "table", "output format: table or json")
+	output := flag.String("o", "", "output file, standard output by default; for download the target file or folder")
+	useTLS := flag.Bool("tls", false, "dial the server over TLS, implied by -ca and -cert")
+	var tlsOptions services.TLSOptions
+	flag.StringVar(&tlsOptions.CAFile, "ca", "", "PEM bundle of the CAs the server certificate is signed by, the system roots if empty")
+	flag.StringVar(&tlsOptions.CertFile, "cert", "", "PEM client certificate for servers that require one")
+	flag.StringVar(&tlsOptions.KeyFile, "key", "", "PEM private key of the client certificate")
+	flag.StringVar(&tlsOptions.ServerName, "server-name", "", "name the server certificate is checked against, the host of -addr if empty")
+	token := flag.String("token", "", "bearer token sent to the server, $IMAGE_CLIENT_TOKEN if empty")
+	var tracingOptions tracing.Options
+	flag.StringVar(&tracingOptions.Exporter, "trace", tracing.ExporterNone, "where spans are exported to: "+strings.Join(tracing.Exporters(), ", "))
+	flag.StringVar(&tracingOptions.File, "trace-file", "", "file the stdout exporter appends spans to, standard output if empty")
+	flag.StringVar(&tracingOptions.OTLPEndpoint, "otlp-endpoint", "localhost:4317", "host:port of the collector the otlp exporter sends spans to")
+	flag.BoolVar(&tracingOptions.OTLPInsecure, "otlp-insecure", false, "send spans to the collector without TLS")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(exitUsage)
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", *format)
+		os.Exit(exitUsage)
+	}
+
+	secure := *useTLS || tlsOptions.CAFile != "" || tlsOptions.CertFile != ""
+	transportCredentials := insecure.NewCredentials()
+	if secure {
+		var err error
+		transportCredentials, err = services.NewTLSCredentials(tlsOptions)
+		if err != nil {
+			log.Fatal("cannot set up TLS: ", err)
+		}
+	}
+	if *token == "" {
+		// the environment is not the flag default, so that the usage does not print the token
+		*token = os.Getenv("IMAGE_CLIENT_TOKEN")
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), "image-client", tracingOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCredentials),
+		services.TracingDialOption(),
+	}
+	if *token != "" {
+		if !secure {
+			log.Print("sending the token over a plain text connection")
+		}
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(services.NewTokenCredentials(*token)))
+	}
 
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	log.Printf("dial server %s", *address)
+
+	conn, err := grpc.Dial(*address, dialOptions...)
 	if err != nil {
 		log.Fatal("cannot dial server: ", err)
 	}
 	defer conn.Close()
 
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
 	imageClient := services.NewImageClient(conn)
-	imageClient.UploadImage("./client/tmp/panda.jpg")
+	out := &printer{format: *format, path: *output}
+
+	// the calls of one command share a trace
+	ctx, span := tracer.Start(ctx, "client "+flag.Arg(0))
+	err = cmd.run(ctx, imageClient, out, flag.Args()[1:])
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+	flushSpans(shutdownTracing)
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "%v\nusage: client [flags] %s %s\n", err, flag.Arg(0), cmd.usage)
+		cancel()
+		conn.Close()
+		os.Exit(exitUsage)
+	}
+	if err != nil {
+		log.Print(err)
+		cancel()
+		conn.Close()
+		os.Exit(exitFailure)
+	}
+}
+
+// flushSpans exports the spans that are left before the client exits
+func flushSpans(shutdownTracing func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("cannot flush spans: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: client [flags] <command> [args]\n\ncommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
 
-	imageClient.GetAndStoreAllImageInfos()
+	fmt.Fprintf(os.Stderr, "\nflags:\n")
+	flag.PrintDefaults()
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes command results as a table or as json, to a file or to the standard output
+type printer struct {
+	format string
+	path   string
+}
+
+// print writes the value as json, or the rows under the header as a table
+func (p *printer) print(value interface{}, header []string, rows [][]string) error {
+	var writer io.Writer = os.Stdout
+	if p.path != "" {
+		file, err := os.Create(p.path)
+		if err != nil {
+			return fmt.Errorf("cannot create output file: %w", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	if p.format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(value)
+		if err != nil {
+			return fmt.Errorf("cannot write output: %w", err)
+		}
+		return nil
+	}
+
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	err := table.Flush()
+	if err != nil {
+		return fmt.Errorf("cannot write output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DirectoryUploadOptions select the files of a directory upload and how they are sent
+type DirectoryUploadOptions struct {
+	// Extensions limits the upload to files with one of these extensions, such as ".jpg"
+	Extensions []string
+	// Pattern is a glob the files must match, against the base name
+	// or against the slash separated path relative to the root if it contains a slash
+	Pattern string
+	// Workers is the number of concurrent uploads, the server's stream limit if zero
+	Workers int
+	// SkipExisting skips files whose checksum matches an image already on the server
+	SkipExisting bool
+	// ConflictPolicy decides what the server does if another image already has the name
+	ConflictPolicy protos.ConflictPolicy
+}
+
+// FileUploadResult is the outcome of uploading one file of a directory
+type FileUploadResult struct {
+	Path     string
+	Size     int64
+	Response *protos.UploadImageResponse
+	Skipped  bool
+	Err      error
+}
+
+// DirectoryUploadReport sums up a directory upload, its results are in walk order
+type DirectoryUploadReport struct {
+	Results   []FileUploadResult
+	Uploaded  int
+	Skipped   int
+	Failed    int
+	BytesSent int64
+	Duration  time.Duration
+}
+
+// UploadDirectory walks the tree under root and uploads the matching files with a bounded pool of workers.
+// A file that fails does not stop the others, its error is kept in the report.
+// The returned error is only set when the upload cannot run at all.
+func (imageClient *ImageClient) UploadDirectory(ctx context.Context, root string, opts DirectoryUploadOptions) (*DirectoryUploadReport, error) {
+	ctx, span := tracer.Start(ctx, "ImageClient.UploadDirectory")
+	report, err := imageClient.uploadDirectory(ctx, root, opts)
+	if report != nil {
+		span.SetAttributes(
+			attribute.Int("files.uploaded", report.Uploaded),
+			attribute.Int("files.skipped", report.Skipped),
+			attribute.Int("files.failed", report.Failed),
+		)
+	}
+	endSpan(span, err)
+	return report, err
+}
+
+func (imageClient *ImageClient) uploadDirectory(ctx context.Context, root string, opts DirectoryUploadOptions) (*DirectoryUploadReport, error) {
+	start := time.Now()
+
+	if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	paths, err := findImageFiles(root, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		serverInfo, err := imageClient.GetServerInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		workers = int(serverInfo.GetMaxStreamConns())
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var existing map[string]bool
+	if opts.SkipExisting {
+		existing, err = imageClient.checksums(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]FileUploadResult, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job] = imageClient.uploadDirectoryFile(ctx, paths[job], existing, opts.ConflictPolicy)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &DirectoryUploadReport{Results: results}
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			report.Failed++
+		case result.Skipped:
+			report.Skipped++
+		default:
+			report.Uploaded++
+			report.BytesSent += result.Size
+		}
+	}
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+func (imageClient *ImageClient) uploadDirectoryFile(ctx context.Context, path string, existing map[string]bool, conflictPolicy protos.ConflictPolicy) FileUploadResult {
+	result := FileUploadResult{Path: path}
+
+	file, err := os.Open(path)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot open image file: %w", err)
+		return result
+	}
+	defer file.Close()
+
+	if existing != nil {
+		hash := sha256.New()
+		result.Size, err = io.Copy(hash, file)
+		if err != nil {
+			result.Err = fmt.Errorf("cannot read image file: %w", err)
+			return result
+		}
+		if existing[hex.EncodeToString(hash.Sum(nil))] {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	res, err := imageClient.UploadImage(ctx, file, UploadOptions{
+		Name:           filepath.Base(path),
+		ConflictPolicy: conflictPolicy,
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Response = res
+	result.Size = int64(res.GetSize())
+	return result
+}
+
+// checksums returns the checksums of all the images on the server, deleted ones included
+func (imageClient *ImageClient) checksums(ctx context.Context) (map[string]bool, error) {
+	imageInfos, err := imageClient.GetImageInfoList(ctx, &protos.GetImageInfoListRequest{IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]bool, len(imageInfos))
+	for _, info := range imageInfos {
+		if info.GetChecksum() != "" {
+			checksums[info.GetChecksum()] = true
+		}
+	}
+	return checksums, nil
+}
+
+// findImageFiles lists the regular files under root that match the options, hidden ones are left out
+func findImageFiles(root string, opts DirectoryUploadOptions) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		if len(opts.Extensions) > 0 && !hasExtension(path, opts.Extensions) {
+			return nil
+		}
+		if opts.Pattern != "" {
+			name := entry.Name()
+			if strings.Contains(opts.Pattern, "/") {
+				relPath, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				name = filepath.ToSlash(relPath)
+			}
+			if matched, _ := filepath.Match(opts.Pattern, name); !matched {
+				return nil
+			}
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk directory: %w", err)
+	}
+	return paths, nil
+}
+
+func hasExtension(path string, extensions []string) bool {
+	ext := filepath.Ext(path)
+	for _, extension := range extensions {
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		if strings.EqualFold(ext, extension) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors returned by ImageClient wrap one of these, so callers can check them with errors.Is
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+	ErrResourceExhausted  = errors.New("resource exhausted")
+	ErrUnauthenticated    = errors.New("unauthenticated")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrUnavailable        = errors.New("server unavailable")
+	ErrCanceled           = errors.New("canceled")
+	ErrDeadlineExceeded   = errors.New("deadline exceeded")
+	ErrInternal           = errors.New("internal server error")
+)
+
+var errorsByCode = map[codes.Code]error{
+	codes.NotFound:           ErrNotFound,
+	codes.AlreadyExists:      ErrAlreadyExists,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.OutOfRange:         ErrInvalidArgument,
+	codes.FailedPrecondition: ErrFailedPrecondition,
+	codes.ResourceExhausted:  ErrResourceExhausted,
+	codes.Unauthenticated:    ErrUnauthenticated,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.Unavailable:        ErrUnavailable,
+	codes.Aborted:            ErrUnavailable,
+	codes.Canceled:           ErrCanceled,
+	codes.DeadlineExceeded:   ErrDeadlineExceeded,
+}
+
+// Error is a failed call to the image service
+type Error struct {
+	// Op describes what the client was doing, such as "cannot upload image"
+	Op     string
+	Status *status.Status
+	kind   error
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", err.Op, err.Status.Message(), err.Status.Code())
+}
+
+// Unwrap returns the sentinel error that matches the status code
+func (err *Error) Unwrap() error {
+	return err.kind
+}
+
+// GRPCStatus lets status.FromError and status.Code see the original status
+func (err *Error) GRPCStatus() *status.Status {
+	return err.Status
+}
+
+// newError wraps the error of a call into an Error,
+// errors that are not a status or a context error are returned with just the op added
+func newError(op string, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		switch {
+		case errors.Is(err, context.Canceled):
+			st = status.New(codes.Canceled, err.Error())
+		case errors.Is(err, context.DeadlineExceeded):
+			st = status.New(codes.DeadlineExceeded, err.Error())
+		default:
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	kind, ok := errorsByCode[st.Code()]
+	if !ok {
+		kind = ErrInternal
+	}
+	return &Error{Op: op, Status: st, kind: kind}
+}
+
+// RetryAfter returns how long the server asked the client to wait before trying the call again.
+// A busy server sends it with ResourceExhausted errors.
+func RetryAfter(err error) (time.Duration, bool) {
+	var st *status.Status
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		st = clientErr.Status
+	} else if s, ok := status.FromError(err); ok {
+		st = s
+	} else {
+		return 0, false
+	}
+
+	for _, detail := range st.Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -1,19 +1,30 @@
 package services
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/navruz-rakhimov/tages-project/protos"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	uploadChunkSize   = 1024
+	maxUploadAttempts = 5
+	uploadRetryDelay  = 500 * time.Millisecond
+)
+
+// ImageClient calls the image service. Its methods never exit the process,
+// failures are returned as errors that wrap one of the Err values of this package.
 type ImageClient struct {
 	service protos.ImageServiceClient
 }
@@ -24,90 +35,382 @@ func NewImageClient(cc *grpc.ClientConn) *ImageClient {
 	return &ImageClient{service}
 }
 
-func (imageClient *ImageClient) GetAndStoreAllImageInfos() {
+// GetImageInfoList calls get image info list RPC page by page and returns the infos of all the pages
+func (imageClient *ImageClient) GetImageInfoList(ctx context.Context, req *protos.GetImageInfoListRequest) ([]*protos.ImageFullInfo, error) {
+	ctx, span := tracer.Start(ctx, "ImageClient.GetImageInfoList")
+	imagesInfoList, err := imageClient.getImageInfoList(ctx, req)
+	span.SetAttributes(attribute.Int("images", len(imagesInfoList)))
+	endSpan(span, err)
+	return imagesInfoList, err
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
+func (imageClient *ImageClient) getImageInfoList(ctx context.Context, req *protos.GetImageInfoListRequest) ([]*protos.ImageFullInfo, error) {
+	imagesInfoList := make([]*protos.ImageFullInfo, 0)
+	for {
+		imagesInfoListResponse, err := imageClient.service.GetImageInfoList(ctx, req)
+		if err != nil {
+			return nil, newError("cannot get image info list", err)
+		}
 
-	imagesInfoListResponse, err := imageClient.service.GetImageInfoList(ctx, &protos.Empty{})
-	if err != nil {
-		log.Fatal("failed to get image info list:", err)
-		return
+		imagesInfoList = append(imagesInfoList, imagesInfoListResponse.GetImageInfos()...)
+		req.PageToken = imagesInfoListResponse.GetNextPageToken()
+		if req.PageToken == "" {
+			break
+		}
 	}
 
-	imagesInfoList := imagesInfoListResponse.GetImageInfos()
-	bytes, err := json.Marshal(imagesInfoList)
+	return imagesInfoList, nil
+}
 
-	file, err := os.Create("./client/tmp/images_info.json")
+// GetImageInfo calls get image info RPC for the image with the given id
+func (imageClient *ImageClient) GetImageInfo(ctx context.Context, imageID string) (*protos.ImageFullInfo, error) {
+	res, err := imageClient.service.GetImageInfo(ctx, &protos.GetImageInfoRequest{Id: imageID})
 	if err != nil {
-		log.Fatal(err)
+		return nil, newError("cannot get image info", err)
 	}
-	defer file.Close()
+	return res, nil
+}
+
+// GetServerInfo calls get server info RPC for the limits of the server
+func (imageClient *ImageClient) GetServerInfo(ctx context.Context) (*protos.ServerInfo, error) {
+	res, err := imageClient.service.GetServerInfo(ctx, &protos.Empty{})
+	if err != nil {
+		return nil, newError("cannot get server info", err)
+	}
+	return res, nil
+}
 
-	file.Write(bytes)
-	file.Sync()
+// UploadOptions describe the image that is uploaded
+type UploadOptions struct {
+	// Name is the image name on the server
+	Name string
+	// Type is the declared image type, the extension of Name if empty
+	Type string
+	// Size is the number of bytes the reader yields, if zero a seekable reader is measured
+	Size int64
+	// ConflictPolicy decides what the server does if another image already has the name
+	ConflictPolicy protos.ConflictPolicy
 }
 
-// UploadImage calls upload image RPC
-func (imageClient *ImageClient) UploadImage(imagePath string) {
-	_, imageName := filepath.Split(imagePath)
+// UploadImage uploads the image that the reader yields.
+// A seekable reader is uploaded in a resumable upload session: when the stream breaks
+// the upload continues from the bytes the server has already committed.
+// Any other reader is sent in a single stream that cannot be resumed.
+func (imageClient *ImageClient) UploadImage(ctx context.Context, image io.Reader, opts UploadOptions) (*protos.UploadImageResponse, error) {
+	ctx, span := tracer.Start(ctx, "ImageClient.UploadImage", trace.WithAttributes(attribute.String("image.name", opts.Name)))
+	res, err := imageClient.uploadImage(ctx, image, opts)
+	endSpan(span, err)
+	return res, err
+}
+
+func (imageClient *ImageClient) uploadImage(ctx context.Context, image io.Reader, opts UploadOptions) (*protos.UploadImageResponse, error) {
+	info := &protos.ImageInfo{
+		ImageType:      opts.Type,
+		ImageName:      opts.Name,
+		ConflictPolicy: opts.ConflictPolicy,
+	}
+	if info.ImageType == "" {
+		info.ImageType = filepath.Ext(opts.Name)
+	}
+
+	seeker, ok := image.(io.ReadSeeker)
+	if !ok {
+		// the server only holds the declared bytes of its upload budget for the stream
+		info.Size = uint64(opts.Size)
+		return imageClient.streamImage(ctx, info, image)
+	}
+
+	size := opts.Size
+	if size == 0 {
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, fmt.Errorf("cannot measure image: %w", err)
+		}
+		size = end
+	}
+
+	startRes, err := imageClient.service.StartUpload(ctx, &protos.StartUploadRequest{
+		Info: info,
+		Size: uint64(size),
+	})
+	if err != nil {
+		return nil, newError("cannot start upload", err)
+	}
+	uploadID := startRes.GetUploadId()
+
+	for attempt := 1; ; attempt++ {
+		res, err := imageClient.sendImageChunks(ctx, uploadID, seeker, attempt > 1)
+		if err == nil {
+			return res, nil
+		}
+		if attempt == maxUploadAttempts || !isRetryable(err) || ctx.Err() != nil {
+			return nil, newError("cannot upload image", err)
+		}
+
+		delay := time.Duration(attempt) * uploadRetryDelay
+		if retryAfter, ok := RetryAfter(err); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+		trace.SpanFromContext(ctx).AddEvent("retry upload", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+			attribute.Int64("delay_ms", delay.Milliseconds()),
+		))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, newError("cannot upload image", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
 
+// UploadFile uploads the image file under its file name
+func (imageClient *ImageClient) UploadFile(ctx context.Context, imagePath string, conflictPolicy protos.ConflictPolicy) (*protos.UploadImageResponse, error) {
 	file, err := os.Open(imagePath)
 	if err != nil {
-		log.Fatal("cannot open image file: ", err)
+		return nil, fmt.Errorf("cannot open image file: %w", err)
 	}
 	defer file.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	return imageClient.UploadImage(ctx, file, UploadOptions{
+		Name:           filepath.Base(imagePath),
+		ConflictPolicy: conflictPolicy,
+	})
+}
+
+// sendImageChunks streams the image to the upload session,
+// on resume it starts at the size the server has already committed.
+// An upload that was saved while the response got lost is not sent again.
+func (imageClient *ImageClient) sendImageChunks(ctx context.Context, uploadID string, image io.ReadSeeker, resume bool) (*protos.UploadImageResponse, error) {
+	offset := int64(0)
+	if resume {
+		res, err := imageClient.service.GetUploadStatus(ctx, &protos.GetUploadStatusRequest{UploadId: uploadID})
+		if err != nil {
+			return nil, err
+		}
+		if res.GetImageId() != "" {
+			return imageClient.savedUpload(ctx, res)
+		}
+		offset = int64(res.GetCommittedSize())
+	}
+
+	_, err := image.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("cannot seek image: %w", err)
+	}
 
 	stream, err := imageClient.service.UploadImage(ctx)
 	if err != nil {
-		log.Fatal("cannot upload image: ", err)
+		return nil, err
+	}
+
+	buffer := make([]byte, uploadChunkSize)
+	for sent := false; ; sent = true {
+		n, err := io.ReadFull(image, buffer)
+		// an empty image still needs one chunk to complete the upload
+		if err == io.EOF && sent {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("cannot read chunk to buffer: %w", err)
+		}
+
+		req := &protos.UploadImageRequest{
+			Data: &protos.UploadImageRequest_Chunk{
+				Chunk: &protos.UploadChunk{
+					UploadId: uploadID,
+					Offset:   uint64(offset),
+					Data:     buffer[:n],
+				},
+			},
+		}
+
+		err = stream.Send(req)
+		if err == io.EOF {
+			// the server has ended the stream, the actual error comes with the response
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset += int64(n)
 	}
 
-	req := &protos.UploadImageRequest{
+	return stream.CloseAndRecv()
+}
+
+// savedUpload returns the response the server sent for an upload that it saved
+func (imageClient *ImageClient) savedUpload(ctx context.Context, res *protos.GetUploadStatusResponse) (*protos.UploadImageResponse, error) {
+	info, err := imageClient.service.GetImageInfo(ctx, &protos.GetImageInfoRequest{Id: res.GetImageId()})
+	if err != nil {
+		return nil, err
+	}
+	return &protos.UploadImageResponse{
+		Id:        res.GetImageId(),
+		ImageName: info.GetImageName(),
+		Size:      uint32(res.GetSize()),
+	}, nil
+}
+
+// streamImage sends the image info followed by the image data in a single stream
+func (imageClient *ImageClient) streamImage(ctx context.Context, info *protos.ImageInfo, image io.Reader) (*protos.UploadImageResponse, error) {
+	stream, err := imageClient.service.UploadImage(ctx)
+	if err != nil {
+		return nil, newError("cannot upload image", err)
+	}
+
+	err = stream.Send(&protos.UploadImageRequest{
 		Data: &protos.UploadImageRequest_Info{
-			Info: &protos.ImageInfo{
-				ImageType: filepath.Ext(imagePath),
-				ImageName: imageName,
+			Info: info,
+		},
+	})
+	if err != nil && err != io.EOF {
+		return nil, newError("cannot send image info", err)
+	}
+
+	buffer := make([]byte, uploadChunkSize)
+	for err == nil {
+		var n int
+		n, err = image.Read(buffer)
+		if n == 0 {
+			continue
+		}
+
+		sendErr := stream.Send(&protos.UploadImageRequest{
+			Data: &protos.UploadImageRequest_ChunkData{
+				ChunkData: buffer[:n],
 			},
+		})
+		if sendErr == io.EOF {
+			// the server has ended the stream, the actual error comes with the response
+			break
+		}
+		if sendErr != nil {
+			return nil, newError("cannot send chunk data", sendErr)
+		}
+	}
+	if err != nil && err != io.EOF {
+		stream.CloseSend()
+		return nil, fmt.Errorf("cannot read chunk to buffer: %w", err)
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, newError("cannot upload image", err)
+	}
+	return res, nil
+}
+
+// isRetryable reports whether the upload can be resumed after the error
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// DownloadImage calls download image RPC for the image with the given id and writes it to w
+func (imageClient *ImageClient) DownloadImage(ctx context.Context, imageID string, w io.Writer) (*protos.ImageInfo, error) {
+	stream, err := imageClient.service.DownloadImage(ctx, &protos.DownloadImageRequest{
+		Image: &protos.DownloadImageRequest_Id{
+			Id: imageID,
 		},
+	})
+	if err != nil {
+		return nil, newError("cannot download image", err)
 	}
+	return receiveImage(stream, w)
+}
 
-	err = stream.Send(req)
+// DownloadImageByName calls download image RPC for the image with the given name and writes it to w
+func (imageClient *ImageClient) DownloadImageByName(ctx context.Context, imageName string, w io.Writer) (*protos.ImageInfo, error) {
+	stream, err := imageClient.service.DownloadImage(ctx, &protos.DownloadImageRequest{
+		Image: &protos.DownloadImageRequest_ImageName{
+			ImageName: imageName,
+		},
+	})
 	if err != nil {
-		log.Fatal("cannot send image info to server: ", err, stream.RecvMsg(nil))
+		return nil, newError("cannot download image", err)
 	}
+	return receiveImage(stream, w)
+}
 
-	reader := bufio.NewReader(file)
-	buffer := make([]byte, 1024)
+// DownloadImageVariant calls get image variant RPC for a resized variant of the image and writes it to w
+func (imageClient *ImageClient) DownloadImageVariant(ctx context.Context, imageID string, variant string, w io.Writer) (*protos.ImageInfo, error) {
+	stream, err := imageClient.service.GetImageVariant(ctx, &protos.GetImageVariantRequest{
+		Id:      imageID,
+		Variant: variant,
+	})
+	if err != nil {
+		return nil, newError("cannot download image variant", err)
+	}
+	return receiveImage(stream, w)
+}
+
+// downloadStream is a server stream that sends image info followed by chunks
+type downloadStream interface {
+	Recv() (*protos.DownloadImageResponse, error)
+}
+
+// receiveImage writes the chunks of the stream to w and returns the image info that comes first
+func receiveImage(stream downloadStream, w io.Writer) (*protos.ImageInfo, error) {
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, newError("cannot receive image info", err)
+	}
+	info := res.GetInfo()
+	if info == nil {
+		return nil, errors.New("cannot receive image info: stream starts with image data")
+	}
 
 	for {
-		n, err := reader.Read(buffer)
+		res, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			log.Fatal("cannot read chunk to buffer: ", err)
-		}
-
-		req := &protos.UploadImageRequest{
-			Data: &protos.UploadImageRequest_ChunkData{
-				ChunkData: buffer[:n],
-			},
+			return nil, newError("cannot receive chunk data", err)
 		}
 
-		err = stream.Send(req)
+		_, err = w.Write(res.GetChunkData())
 		if err != nil {
-			log.Fatal("cannot send chunk to server: ", err)
+			return nil, fmt.Errorf("cannot write chunk: %w", err)
 		}
 	}
 
-	res, err := stream.CloseAndRecv()
+	return info, nil
+}
+
+// DeleteImage calls delete image RPC, the image goes to the trash on the server
+func (imageClient *ImageClient) DeleteImage(ctx context.Context, imageID string) error {
+	_, err := imageClient.service.DeleteImage(ctx, &protos.DeleteImageRequest{Id: imageID})
 	if err != nil {
-		log.Fatal("cannot receive response: ", err)
+		return newError("cannot delete image", err)
 	}
+	return nil
+}
 
-	log.Printf("image uploaded with id: %s, size: %d", res.GetId(), res.GetSize())
+// RestoreImage calls restore image RPC to bring the image back from the trash
+func (imageClient *ImageClient) RestoreImage(ctx context.Context, imageID string) error {
+	_, err := imageClient.service.RestoreImage(ctx, &protos.RestoreImageRequest{Id: imageID})
+	if err != nil {
+		return newError("cannot restore image", err)
+	}
+	return nil
+}
+
+// RenameImage calls rename image RPC to give the image a new name
+func (imageClient *ImageClient) RenameImage(ctx context.Context, imageID string, imageName string) (*protos.RenameImageResponse, error) {
+	res, err := imageClient.service.RenameImage(ctx, &protos.RenameImageRequest{
+		Id:        imageID,
+		ImageName: imageName,
+	})
+	if err != nil {
+		return nil, newError("cannot rename image", err)
+	}
+	return res, nil
 }
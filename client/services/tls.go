@@ -0,0 +1,62 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions select how the client checks the server and how it proves its own identity
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the CAs the server certificate is signed by, the system roots if empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate for servers that require one, none if empty
+	CertFile string
+	KeyFile  string
+	// ServerName is checked against the server certificate instead of the host dialed
+	ServerName string
+}
+
+// NewTLSCredentials returns transport credentials to dial the image server over TLS.
+// The client certificate is read again on every handshake, so new connections pick up a rotated one.
+func NewTLSCredentials(opts TLSOptions) (credentials.TransportCredentials, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+	}
+
+	if opts.CAFile != "" {
+		bundle, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("cannot load CA bundle: no certificates found")
+		}
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	if opts.CertFile != "" {
+		// a broken certificate should fail here rather than on the first handshake
+		_, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load client certificate: %w", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	return credentials.NewTLS(config), nil
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// tokenCredentials sends a bearer token with every call
+type tokenCredentials struct {
+	token string
+}
+
+// NewTokenCredentials returns credentials for grpc.WithPerRPCCredentials that authenticate with the token.
+// They also work over plain text connections, where the token can be read by anyone on the way.
+func NewTokenCredentials(token string) credentials.PerRPCCredentials {
+	return tokenCredentials{token}
+}
+
+func (creds tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + creds.token,
+	}, nil
+}
+
+func (creds tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// tracer starts the spans of the image client methods that make more than one call
+var tracer = otel.Tracer("github.com/navruz-rakhimov/tages-project/client/services")
+
+// TracingDialOption traces every call of the connection and sends the trace context
+// of the call in its metadata, so that the spans of the server join the trace of the client.
+// The spans go to the global tracer provider and the context is written by the global propagator.
+func TracingDialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// endSpan marks the span as failed if err is set and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
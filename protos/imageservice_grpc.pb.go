@@ -23,7 +23,16 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ImageServiceClient interface {
 	UploadImage(ctx context.Context, opts ...grpc.CallOption) (ImageService_UploadImageClient, error)
-	GetImageInfoList(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetImageInfoListResponse, error)
+	StartUpload(ctx context.Context, in *StartUploadRequest, opts ...grpc.CallOption) (*StartUploadResponse, error)
+	GetUploadStatus(ctx context.Context, in *GetUploadStatusRequest, opts ...grpc.CallOption) (*GetUploadStatusResponse, error)
+	GetImageInfoList(ctx context.Context, in *GetImageInfoListRequest, opts ...grpc.CallOption) (*GetImageInfoListResponse, error)
+	DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (ImageService_DownloadImageClient, error)
+	GetImageVariant(ctx context.Context, in *GetImageVariantRequest, opts ...grpc.CallOption) (ImageService_GetImageVariantClient, error)
+	DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error)
+	RestoreImage(ctx context.Context, in *RestoreImageRequest, opts ...grpc.CallOption) (*RestoreImageResponse, error)
+	GetImageInfo(ctx context.Context, in *GetImageInfoRequest, opts ...grpc.CallOption) (*ImageFullInfo, error)
+	RenameImage(ctx context.Context, in *RenameImageRequest, opts ...grpc.CallOption) (*RenameImageResponse, error)
+	GetServerInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServerInfo, error)
 }
 
 type imageServiceClient struct {
@@ -68,7 +77,25 @@ func (x *imageServiceUploadImageClient) CloseAndRecv() (*UploadImageResponse, er
 	return m, nil
 }
 
-func (c *imageServiceClient) GetImageInfoList(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetImageInfoListResponse, error) {
+func (c *imageServiceClient) StartUpload(ctx context.Context, in *StartUploadRequest, opts ...grpc.CallOption) (*StartUploadResponse, error) {
+	out := new(StartUploadResponse)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/StartUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) GetUploadStatus(ctx context.Context, in *GetUploadStatusRequest, opts ...grpc.CallOption) (*GetUploadStatusResponse, error) {
+	out := new(GetUploadStatusResponse)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/GetUploadStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) GetImageInfoList(ctx context.Context, in *GetImageInfoListRequest, opts ...grpc.CallOption) (*GetImageInfoListResponse, error) {
 	out := new(GetImageInfoListResponse)
 	err := c.cc.Invoke(ctx, "/imageservice.ImageService/GetImageInfoList", in, out, opts...)
 	if err != nil {
@@ -77,12 +104,130 @@ func (c *imageServiceClient) GetImageInfoList(ctx context.Context, in *Empty, op
 	return out, nil
 }
 
+func (c *imageServiceClient) DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (ImageService_DownloadImageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[1], "/imageservice.ImageService/DownloadImage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageServiceDownloadImageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageService_DownloadImageClient interface {
+	Recv() (*DownloadImageResponse, error)
+	grpc.ClientStream
+}
+
+type imageServiceDownloadImageClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageServiceDownloadImageClient) Recv() (*DownloadImageResponse, error) {
+	m := new(DownloadImageResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageServiceClient) GetImageVariant(ctx context.Context, in *GetImageVariantRequest, opts ...grpc.CallOption) (ImageService_GetImageVariantClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[2], "/imageservice.ImageService/GetImageVariant", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageServiceGetImageVariantClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageService_GetImageVariantClient interface {
+	Recv() (*DownloadImageResponse, error)
+	grpc.ClientStream
+}
+
+type imageServiceGetImageVariantClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageServiceGetImageVariantClient) Recv() (*DownloadImageResponse, error) {
+	m := new(DownloadImageResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageServiceClient) DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error) {
+	out := new(DeleteImageResponse)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/DeleteImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) RestoreImage(ctx context.Context, in *RestoreImageRequest, opts ...grpc.CallOption) (*RestoreImageResponse, error) {
+	out := new(RestoreImageResponse)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/RestoreImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) GetImageInfo(ctx context.Context, in *GetImageInfoRequest, opts ...grpc.CallOption) (*ImageFullInfo, error) {
+	out := new(ImageFullInfo)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/GetImageInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) RenameImage(ctx context.Context, in *RenameImageRequest, opts ...grpc.CallOption) (*RenameImageResponse, error) {
+	out := new(RenameImageResponse)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/RenameImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) GetServerInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ServerInfo, error) {
+	out := new(ServerInfo)
+	err := c.cc.Invoke(ctx, "/imageservice.ImageService/GetServerInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ImageServiceServer is the server API for ImageService service.
 // All implementations must embed UnimplementedImageServiceServer
 // for forward compatibility
 type ImageServiceServer interface {
 	UploadImage(ImageService_UploadImageServer) error
-	GetImageInfoList(context.Context, *Empty) (*GetImageInfoListResponse, error)
+	StartUpload(context.Context, *StartUploadRequest) (*StartUploadResponse, error)
+	GetUploadStatus(context.Context, *GetUploadStatusRequest) (*GetUploadStatusResponse, error)
+	GetImageInfoList(context.Context, *GetImageInfoListRequest) (*GetImageInfoListResponse, error)
+	DownloadImage(*DownloadImageRequest, ImageService_DownloadImageServer) error
+	GetImageVariant(*GetImageVariantRequest, ImageService_GetImageVariantServer) error
+	DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error)
+	RestoreImage(context.Context, *RestoreImageRequest) (*RestoreImageResponse, error)
+	GetImageInfo(context.Context, *GetImageInfoRequest) (*ImageFullInfo, error)
+	RenameImage(context.Context, *RenameImageRequest) (*RenameImageResponse, error)
+	GetServerInfo(context.Context, *Empty) (*ServerInfo, error)
 	mustEmbedUnimplementedImageServiceServer()
 }
 
@@ -93,9 +238,36 @@ type UnimplementedImageServiceServer struct {
 func (UnimplementedImageServiceServer) UploadImage(ImageService_UploadImageServer) error {
 	return status.Errorf(codes.Unimplemented, "method UploadImage not implemented")
 }
-func (UnimplementedImageServiceServer) GetImageInfoList(context.Context, *Empty) (*GetImageInfoListResponse, error) {
+func (UnimplementedImageServiceServer) StartUpload(context.Context, *StartUploadRequest) (*StartUploadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartUpload not implemented")
+}
+func (UnimplementedImageServiceServer) GetUploadStatus(context.Context, *GetUploadStatusRequest) (*GetUploadStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUploadStatus not implemented")
+}
+func (UnimplementedImageServiceServer) GetImageInfoList(context.Context, *GetImageInfoListRequest) (*GetImageInfoListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetImageInfoList not implemented")
 }
+func (UnimplementedImageServiceServer) DownloadImage(*DownloadImageRequest, ImageService_DownloadImageServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadImage not implemented")
+}
+func (UnimplementedImageServiceServer) GetImageVariant(*GetImageVariantRequest, ImageService_GetImageVariantServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetImageVariant not implemented")
+}
+func (UnimplementedImageServiceServer) DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteImage not implemented")
+}
+func (UnimplementedImageServiceServer) RestoreImage(context.Context, *RestoreImageRequest) (*RestoreImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreImage not implemented")
+}
+func (UnimplementedImageServiceServer) GetImageInfo(context.Context, *GetImageInfoRequest) (*ImageFullInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetImageInfo not implemented")
+}
+func (UnimplementedImageServiceServer) RenameImage(context.Context, *RenameImageRequest) (*RenameImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameImage not implemented")
+}
+func (UnimplementedImageServiceServer) GetServerInfo(context.Context, *Empty) (*ServerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerInfo not implemented")
+}
 func (UnimplementedImageServiceServer) mustEmbedUnimplementedImageServiceServer() {}
 
 // UnsafeImageServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -135,8 +307,44 @@ func (x *imageServiceUploadImageServer) Recv() (*UploadImageRequest, error) {
 	return m, nil
 }
 
+func _ImageService_StartUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).StartUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/StartUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).StartUpload(ctx, req.(*StartUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_GetUploadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUploadStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).GetUploadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/GetUploadStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).GetUploadStatus(ctx, req.(*GetUploadStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ImageService_GetImageInfoList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(GetImageInfoListRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -148,7 +356,139 @@ func _ImageService_GetImageInfoList_Handler(srv interface{}, ctx context.Context
 		FullMethod: "/imageservice.ImageService/GetImageInfoList",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ImageServiceServer).GetImageInfoList(ctx, req.(*Empty))
+		return srv.(ImageServiceServer).GetImageInfoList(ctx, req.(*GetImageInfoListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_DownloadImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).DownloadImage(m, &imageServiceDownloadImageServer{stream})
+}
+
+type ImageService_DownloadImageServer interface {
+	Send(*DownloadImageResponse) error
+	grpc.ServerStream
+}
+
+type imageServiceDownloadImageServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageServiceDownloadImageServer) Send(m *DownloadImageResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageService_GetImageVariant_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetImageVariantRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).GetImageVariant(m, &imageServiceGetImageVariantServer{stream})
+}
+
+type ImageService_GetImageVariantServer interface {
+	Send(*DownloadImageResponse) error
+	grpc.ServerStream
+}
+
+type imageServiceGetImageVariantServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageServiceGetImageVariantServer) Send(m *DownloadImageResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageService_DeleteImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).DeleteImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/DeleteImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).DeleteImage(ctx, req.(*DeleteImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_RestoreImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).RestoreImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/RestoreImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).RestoreImage(ctx, req.(*RestoreImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_GetImageInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetImageInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).GetImageInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/GetImageInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).GetImageInfo(ctx, req.(*GetImageInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_RenameImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).RenameImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/RenameImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).RenameImage(ctx, req.(*RenameImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_GetServerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).GetServerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imageservice.ImageService/GetServerInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).GetServerInfo(ctx, req.(*Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -160,10 +500,38 @@ var ImageService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "imageservice.ImageService",
 	HandlerType: (*ImageServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartUpload",
+			Handler:    _ImageService_StartUpload_Handler,
+		},
+		{
+			MethodName: "GetUploadStatus",
+			Handler:    _ImageService_GetUploadStatus_Handler,
+		},
 		{
 			MethodName: "GetImageInfoList",
 			Handler:    _ImageService_GetImageInfoList_Handler,
 		},
+		{
+			MethodName: "DeleteImage",
+			Handler:    _ImageService_DeleteImage_Handler,
+		},
+		{
+			MethodName: "RestoreImage",
+			Handler:    _ImageService_RestoreImage_Handler,
+		},
+		{
+			MethodName: "GetImageInfo",
+			Handler:    _ImageService_GetImageInfo_Handler,
+		},
+		{
+			MethodName: "RenameImage",
+			Handler:    _ImageService_RenameImage_Handler,
+		},
+		{
+			MethodName: "GetServerInfo",
+			Handler:    _ImageService_GetServerInfo_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -171,6 +539,16 @@ var ImageService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ImageService_UploadImage_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "DownloadImage",
+			Handler:       _ImageService_DownloadImage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetImageVariant",
+			Handler:       _ImageService_GetImageVariant_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "protos/imageservice.proto",
 }
@@ -0,0 +1,170 @@
+// Package auth checks the bearer tokens that callers send in the gRPC metadata or the HTTP headers
+// and puts the identity of the caller into the request context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AdminRole is the role that gives access to the images of every owner
+const AdminRole = "admin"
+
+// ErrInvalidToken is returned by authenticators for tokens they do not accept
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity is the authenticated caller
+type Identity struct {
+	Subject string
+	Admin   bool
+}
+
+// Authenticator turns a bearer token into the identity of the caller
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+type identityKey struct{}
+
+// NewContext returns a context that carries the identity
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity of the caller, there is none when authentication is disabled
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// Interceptor rejects the calls that do not carry a token one of its authenticators accepts
+type Interceptor struct {
+	authenticators []Authenticator
+	// public are the services that can be called without a token
+	public map[string]bool
+}
+
+// NewInterceptor returns an interceptor that tries the authenticators in order
+func NewInterceptor(authenticators ...Authenticator) *Interceptor {
+	return &Interceptor{
+		authenticators: authenticators,
+		public:         make(map[string]bool),
+	}
+}
+
+// AllowUnauthenticated lets calls to the services through without a token, such as the health checks of an orchestrator.
+// Services are given by their full name, such as grpc.health.v1.Health.
+func (interceptor *Interceptor) AllowUnauthenticated(services ...string) {
+	for _, service := range services {
+		interceptor.public[service] = true
+	}
+}
+
+func (interceptor *Interceptor) isPublic(fullMethod string) bool {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return interceptor.public[service]
+}
+
+// Unary returns the interceptor for unary calls
+func (interceptor *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if interceptor.isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := interceptor.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the interceptor for streaming calls
+func (interceptor *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if interceptor.isPublic(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := interceptor.authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{stream, ctx})
+	}
+}
+
+// AuthenticateHTTP checks the bearer token in the Authorization header of an HTTP request
+// and returns the request context with the identity, or a status error if the token is not accepted
+func (interceptor *Interceptor) AuthenticateHTTP(r *http.Request) (context.Context, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, status.Error(codes.Unauthenticated, "cannot authenticate: authorization token is missing")
+	}
+	token, err := parseBearer(header)
+	if err != nil {
+		return nil, err
+	}
+	return interceptor.authenticateToken(r.Context(), token)
+}
+
+func (interceptor *Interceptor) authenticate(ctx context.Context) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return interceptor.authenticateToken(ctx, token)
+}
+
+func (interceptor *Interceptor) authenticateToken(ctx context.Context, token string) (context.Context, error) {
+	rejection := ErrInvalidToken
+	for _, authenticator := range interceptor.authenticators {
+		identity, err := authenticator.Authenticate(token)
+		if errors.Is(err, ErrInvalidToken) {
+			// the reason of an authenticator that recognised the token beats a plain rejection
+			if err != ErrInvalidToken {
+				rejection = err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "cannot authenticate: %v", err)
+		}
+		return NewContext(ctx, identity), nil
+	}
+	return nil, status.Errorf(codes.Unauthenticated, "cannot authenticate: %v", rejection)
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "cannot authenticate: authorization token is missing")
+	}
+	return parseBearer(values[0])
+}
+
+// parseBearer returns the token of an authorization value of the form "Bearer <token>"
+func parseBearer(value string) (string, error) {
+	scheme, token, ok := strings.Cut(value, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", status.Error(codes.Unauthenticated, "cannot authenticate: expected a bearer token")
+	}
+	return token, nil
+}
+
+// identityStream hands the context with the identity to stream handlers
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *identityStream) Context() context.Context {
+	return stream.ctx
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// clockSkew is how far the clocks of the token issuer and the server may be apart
+const clockSkew = 30 * time.Second
+
+// JWTVerifier accepts HS256 JSON web tokens signed with a shared secret.
+// The subject comes from the sub claim, the admin role from a roles claim that contains "admin".
+// Tokens must expire, ones without an exp claim would be valid forever.
+type JWTVerifier struct {
+	secret []byte
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Roles     []string `json:"roles"`
+}
+
+// LoadJWTVerifier reads the secret from a file, surrounding white space is not part of it
+func LoadJWTVerifier(path string) (*JWTVerifier, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read JWT secret: %w", err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) < 32 {
+		return nil, errors.New("cannot use JWT secret: it must be at least 32 bytes long")
+	}
+	return &JWTVerifier{secret: secret}, nil
+}
+
+func (verifier *JWTVerifier) Authenticate(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// not a JWT, it may be meant for another authenticator
+		return nil, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	mac := hmac.New(sha256.New, verifier.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: exp claim is missing", ErrInvalidToken)
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-clockSkew)) {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: sub claim is missing", ErrInvalidToken)
+	}
+
+	identity := &Identity{Subject: claims.Subject}
+	for _, role := range claims.Roles {
+		if role == AdminRole {
+			identity.Admin = true
+		}
+	}
+	return identity, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrInvalidToken)
+	}
+	return nil
+}
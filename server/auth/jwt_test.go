@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func signToken(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTVerifierAuthenticate(t *testing.T) {
+	verifier := &JWTVerifier{secret: testSecret}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name    string
+		claims  map[string]interface{}
+		wantErr bool
+		admin   bool
+	}{
+		{"valid", map[string]interface{}{"sub": "alice", "exp": now + 60}, false, false},
+		{"admin", map[string]interface{}{"sub": "root", "exp": now + 60, "roles": []string{"admin"}}, false, true},
+		{"without exp", map[string]interface{}{"sub": "alice"}, true, false},
+		{"expired", map[string]interface{}{"sub": "alice", "exp": now - 3600}, true, false},
+		{"not valid yet", map[string]interface{}{"sub": "alice", "exp": now + 7200, "nbf": now + 3600}, true, false},
+		{"without sub", map[string]interface{}{"exp": now + 60}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.Authenticate(signToken(t, tt.claims))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("got %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if identity.Subject != tt.claims["sub"] || identity.Admin != tt.admin {
+				t.Errorf("got %+v", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeys accepts static keys read from a file
+type APIKeys struct {
+	// keys are looked up by their hash, so that the lookup does not depend on how much of a guess is right
+	keys map[[sha256.Size]byte]*Identity
+}
+
+// LoadAPIKeys reads a file with one key per line as "<key> <subject> [admin]".
+// Empty lines and lines starting with # are skipped.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open API key file: %w", err)
+	}
+	defer file.Close()
+
+	apiKeys := &APIKeys{keys: make(map[[sha256.Size]byte]*Identity)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != AdminRole) {
+			return nil, fmt.Errorf("cannot parse API key file: line %d: expected <key> <subject> [%s]", line, AdminRole)
+		}
+
+		hash := sha256.Sum256([]byte(fields[0]))
+		if _, ok := apiKeys.keys[hash]; ok {
+			return nil, fmt.Errorf("cannot parse API key file: line %d: duplicate key", line)
+		}
+		apiKeys.keys[hash] = &Identity{
+			Subject: fields[1],
+			Admin:   len(fields) == 3,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read API key file: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+// Len returns the number of keys
+func (apiKeys *APIKeys) Len() int {
+	return len(apiKeys.keys)
+}
+
+func (apiKeys *APIKeys) Authenticate(token string) (*Identity, error) {
+	identity, ok := apiKeys.keys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return identity, nil
+}
@@ -0,0 +1,194 @@
+// Package certs serves TLS certificates from files and picks up new ones when the files are replaced.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader keeps the server certificate and an optional client CA bundle loaded from files.
+// The files are polled and reloaded when they change, so rotating certificates needs no restart.
+type Reloader struct {
+	certPath string
+	keyPath  string
+	caPath   string
+
+	mutex     sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	versions  []fileVersion
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// fileVersion changes whenever a file is written or replaced
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the certificate and key and, if caPath is set, the CA bundle client certificates must be signed by.
+// The files are checked for changes every interval.
+func NewReloader(certPath string, keyPath string, caPath string, interval time.Duration) (*Reloader, error) {
+	reloader := &Reloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		caPath:   caPath,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	err := reloader.reload()
+	if err != nil {
+		return nil, err
+	}
+	go reloader.run(interval)
+
+	return reloader, nil
+}
+
+// TLSConfig returns a server config that takes the current certificate and client CAs on every handshake.
+// With a client CA bundle the clients must present a certificate signed by it.
+func (reloader *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return reloader.current(), nil
+		},
+	}
+}
+
+// RequiresClientCert reports whether clients must present a certificate
+func (reloader *Reloader) RequiresClientCert() bool {
+	return reloader.caPath != ""
+}
+
+// Close stops watching the files
+func (reloader *Reloader) Close() {
+	close(reloader.stop)
+	<-reloader.done
+}
+
+func (reloader *Reloader) current() *tls.Config {
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*reloader.cert},
+		// grpc negotiates HTTP/2 through ALPN, the REST gateway serves HTTP/1.1 clients too
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if reloader.clientCAs != nil {
+		config.ClientCAs = reloader.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+func (reloader *Reloader) paths() []string {
+	paths := []string{reloader.certPath, reloader.keyPath}
+	if reloader.caPath != "" {
+		paths = append(paths, reloader.caPath)
+	}
+	return paths
+}
+
+func (reloader *Reloader) stat() ([]fileVersion, error) {
+	var versions []fileVersion
+	for _, path := range reloader.paths() {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, fileVersion{fileInfo.ModTime(), fileInfo.Size()})
+	}
+	return versions, nil
+}
+
+// changed reports whether any of the files differs from the loaded version
+func (reloader *Reloader) changed() bool {
+	versions, err := reloader.stat()
+	if err != nil {
+		// a file that is missing for a moment is being replaced, it is picked up once it is back
+		return false
+	}
+
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+
+	for i := range versions {
+		if versions[i] != reloader.versions[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// reload reads all the files again, on failure the previously loaded ones stay in use
+func (reloader *Reloader) reload() error {
+	// the versions are taken first, so that a write during the load is seen as a change next time
+	versions, err := reloader.stat()
+	if err != nil {
+		return fmt.Errorf("cannot read certificate files: %w", err)
+	}
+
+	cert, err := tls.LoadX509KeyPair(reloader.certPath, reloader.keyPath)
+	if err != nil {
+		return fmt.Errorf("cannot load certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("cannot parse certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if reloader.caPath != "" {
+		bundle, err := os.ReadFile(reloader.caPath)
+		if err != nil {
+			return fmt.Errorf("cannot read client CA bundle: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(bundle) {
+			return errors.New("cannot load client CA bundle: no certificates found")
+		}
+	}
+
+	reloader.mutex.Lock()
+	reloader.cert = &cert
+	reloader.clientCAs = clientCAs
+	reloader.versions = versions
+	reloader.mutex.Unlock()
+
+	slog.Info("loaded TLS certificate", "subject", leaf.Subject.CommonName, "not_after", leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+func (reloader *Reloader) run(interval time.Duration) {
+	defer close(reloader.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-reloader.stop:
+			return
+		case <-ticker.C:
+			if !reloader.changed() {
+				continue
+			}
+			err := reloader.reload()
+			if err != nil {
+				slog.Error("cannot reload TLS certificate, keeping the previous one", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf for localhost with the given common name
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile replaces the file and moves its modification time forward,
+// so that the change is seen even on file systems with a coarse clock
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	err := os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedName(t *testing.T, reloader *Reloader) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(reloader.current().Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestReloaderReloadsChangedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	folder := t.TempDir()
+	certPath, keyPath := filepath.Join(folder, "cert.pem"), filepath.Join(folder, "key.pem")
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, certPEM, time.Now().Add(-time.Minute))
+	writeFile(t, keyPath, keyPEM, time.Now().Add(-time.Minute))
+
+	reloader, err := NewReloader(certPath, keyPath, "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	defer reloader.Close()
+	if name := servedName(t, reloader); name != "first" {
+		t.Fatalf("serves %q, want first", name)
+	}
+
+	certPEM, keyPEM = ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	writeFile(t, keyPath, keyPEM, time.Now())
+	writeFile(t, certPath, certPEM, time.Now())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for servedName(t, reloader) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatalf("still serves %q after the files changed", servedName(t, reloader))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloaderKeepsCertificateWhenNewOneIsBad(t *testing.T) {
+	ca := newTestCA(t)
+	folder := t.TempDir()
+	certPath, keyPath := filepath.Join(folder, "cert.pem"), filepath.Join(folder, "key.pem")
+	certPEM, keyPEM := ca.issue(t, "good", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, certPEM, time.Now().Add(-time.Minute))
+	writeFile(t, keyPath, keyPEM, time.Now().Add(-time.Minute))
+
+	// the reload is driven by hand, the files are checked once an hour
+	reloader, err := NewReloader(certPath, keyPath, "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	defer reloader.Close()
+
+	otherCertPEM, _ := ca.issue(t, "mismatched", x509.ExtKeyUsageServerAuth)
+	for name, data := range map[string][]byte{
+		"garbage":                    []byte("not a certificate"),
+		"certificate of another key": otherCertPEM,
+	} {
+		writeFile(t, certPath, data, time.Now())
+		if !reloader.changed() {
+			t.Fatalf("%s: change is not seen", name)
+		}
+		if err := reloader.reload(); err == nil {
+			t.Errorf("%s: reload succeeded", name)
+		}
+		if served := servedName(t, reloader); served != "good" {
+			t.Errorf("%s: serves %q, want the previous certificate", name, served)
+		}
+	}
+}
+
+func TestReloaderRequiresClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	folder := t.TempDir()
+	certPath, keyPath, caPath := filepath.Join(folder, "cert.pem"), filepath.Join(folder, "key.pem"), filepath.Join(folder, "ca.pem")
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certPath, certPEM, time.Now())
+	writeFile(t, keyPath, keyPEM, time.Now())
+	writeFile(t, caPath, ca.pem, time.Now())
+
+	reloader, err := NewReloader(certPath, keyPath, caPath, time.Hour)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	defer reloader.Close()
+	if !reloader.RequiresClientCert() {
+		t.Errorf("RequiresClientCert is false with a client CA bundle")
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", reloader.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	handshakes := make(chan error)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handshakes <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCertPEM, clientKeyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangerCertPEM, strangerKeyPEM := newTestCA(t).issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	strangerCert, err := tls.X509KeyPair(strangerCertPEM, strangerKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		wantErr      bool
+	}{
+		{"without client certificate", nil, true},
+		{"with certificate of another CA", []tls.Certificate{strangerCert}, true},
+		{"with client certificate", []tls.Certificate{clientCert}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+				RootCAs:      roots,
+				ServerName:   "localhost",
+				Certificates: tt.certificates,
+			})
+			if err == nil {
+				conn.Close()
+			}
+
+			// with TLS 1.3 the client may finish its handshake before the server rejects it
+			err = <-handshakes
+			if (err != nil) != tt.wantErr {
+				t.Errorf("server handshake: got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
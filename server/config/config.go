@@ -0,0 +1,368 @@
+// Package config loads the settings of the image server from defaults, a YAML file,
+// environment variables and command line flags, each overriding the previous ones.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/server/logging"
+	"github.com/navruz-rakhimov/tages-project/server/services"
+	"github.com/navruz-rakhimov/tages-project/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is prepended to the upper-cased setting name to get its environment variable,
+// so that max-read-conns is set by IMAGE_SERVER_MAX_READ_CONNS
+const envPrefix = "IMAGE_SERVER_"
+
+// Config is the effective configuration of the image server
+type Config struct {
+	Listen           string         `yaml:"listen"`
+	HTTPListen       string         `yaml:"http_listen"`
+	MetricsListen    string         `yaml:"metrics_listen"`
+	StorageRoot      string         `yaml:"storage_root"`
+	StorageDedup     bool           `yaml:"storage_dedup"`
+	MaxReadConns     int64          `yaml:"max_read_conns"`
+	MaxStreamConns   int64          `yaml:"max_stream_conns"`
+	MaxImageSize     ByteSize       `yaml:"max_image_size"`
+	MaxImagePixels   int64          `yaml:"max_image_pixels"`
+	MaxQueueWait     time.Duration  `yaml:"max_queue_wait"`
+	UploadByteBudget ByteSize       `yaml:"upload_byte_budget"`
+	AllowedTypes     []string       `yaml:"allowed_types"`
+	TrashRetention   time.Duration  `yaml:"trash_retention"`
+	UploadTimeout    time.Duration  `yaml:"upload_timeout"`
+	Variants         map[string]int `yaml:"variants"`
+	ShutdownTimeout  time.Duration  `yaml:"shutdown_timeout"`
+	// the resumable uploads are bounded, every one of them holds an open file
+	MaxUploadSessions         int64 `yaml:"max_upload_sessions"`
+	MaxUploadSessionsPerOwner int64 `yaml:"max_upload_sessions_per_owner"`
+	// TLS is served when the certificate and key are set, clients need a certificate signed by TLSClientCA if it is set too
+	TLSCert           string        `yaml:"tls_cert"`
+	TLSKey            string        `yaml:"tls_key"`
+	TLSClientCA       string        `yaml:"tls_client_ca"`
+	TLSReloadInterval time.Duration `yaml:"tls_reload_interval"`
+	// callers must send a bearer token when either file is set, otherwise everyone can access every image
+	AuthKeysFile      string `yaml:"auth_keys_file"`
+	AuthJWTSecretFile string `yaml:"auth_jwt_secret_file"`
+	// spans are exported to an OTLP collector or written as JSON to TracingFile, standard output if empty
+	TracingExporter     string `yaml:"tracing_exporter"`
+	TracingFile         string `yaml:"tracing_file"`
+	TracingOTLPEndpoint string `yaml:"tracing_otlp_endpoint"`
+	TracingOTLPInsecure bool   `yaml:"tracing_otlp_insecure"`
+	LogLevel            string `yaml:"log_level"`
+	LogFormat           string `yaml:"log_format"`
+	// HealthCheckInterval is how often the store is checked for the grpc.health.v1 status
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+	// Reflection lets tools such as grpcurl list the services and their messages
+	Reflection bool `yaml:"reflection"`
+}
+
+// Default returns the configuration used for the settings that are not set anywhere
+func Default() *Config {
+	return &Config{
+		Listen:           ":5001",
+		HTTPListen:       ":8080",
+		MetricsListen:    "localhost:9090",
+		StorageRoot:      filepath.Join("server", "tmp"),
+		MaxReadConns:     100,
+		MaxStreamConns:   10,
+		MaxImageSize:     256 << 20,
+		MaxImagePixels:   100_000_000,
+		MaxQueueWait:     5 * time.Second,
+		UploadByteBudget: 1 << 30,
+		AllowedTypes:     services.ImageFormatNames(),
+		TrashRetention:   7 * 24 * time.Hour,
+		UploadTimeout:    30 * time.Minute,
+		Variants: map[string]int{
+			"thumbnail": 128,
+			"preview":   512,
+		},
+		ShutdownTimeout:   30 * time.Second,
+		TLSReloadInterval: time.Minute,
+		TracingExporter:   tracing.ExporterNone,
+		// the default port of an OTLP collector over gRPC
+		TracingOTLPEndpoint: "localhost:4317",
+		LogLevel:            "info",
+		LogFormat:           logging.FormatText,
+		HealthCheckInterval: 10 * time.Second,
+		// an owner gets a share of the resumable uploads, so that it cannot take them all
+		MaxUploadSessions:         1000,
+		MaxUploadSessionsPerOwner: 20,
+	}
+}
+
+// setting binds a flag, an environment variable and a file key to a field of the config
+type setting struct {
+	name  string
+	usage string
+	value func(cfg *Config) flag.Value
+}
+
+var settings = []setting{
+	{"listen", "address the server listens on", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.Listen) }},
+	{"http-listen", "address the REST gateway listens on, none if empty", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.HTTPListen) }},
+	{"metrics-listen", "address /metrics is served on over HTTP, none if empty", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.MetricsListen) }},
+	{"storage-root", "folder the images are stored in", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.StorageRoot) }},
+	{"storage-dedup", "store the content of identical images once, under its SHA-256", func(cfg *Config) flag.Value { return (*boolValue)(&cfg.StorageDedup) }},
+	{"max-read-conns", "number of image info requests served at once", func(cfg *Config) flag.Value { return (*int64Value)(&cfg.MaxReadConns) }},
+	{"max-stream-conns", "number of uploads and of downloads served at once", func(cfg *Config) flag.Value { return (*int64Value)(&cfg.MaxStreamConns) }},
+	{"max-image-size", "largest image that can be uploaded, such as 256MiB", func(cfg *Config) flag.Value { return &cfg.MaxImageSize }},
+	{"max-image-pixels", "largest width times height of an image that can be uploaded", func(cfg *Config) flag.Value { return (*int64Value)(&cfg.MaxImagePixels) }},
+	{"upload-byte-budget", "bytes all the uploads in flight may hold together, at least max-image-size", func(cfg *Config) flag.Value { return &cfg.UploadByteBudget }},
+	{"max-queue-wait", "how long a request waits for a free slot before it is turned away", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.MaxQueueWait) }},
+	{"allowed-types", "comma separated image formats that can be uploaded", func(cfg *Config) flag.Value { return (*listValue)(&cfg.AllowedTypes) }},
+	{"trash-retention", "how long deleted images can be restored", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.TrashRetention) }},
+	{"upload-timeout", "how long an idle resumable upload is kept", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.UploadTimeout) }},
+	{"max-upload-sessions", "resumable uploads kept at once", func(cfg *Config) flag.Value { return (*int64Value)(&cfg.MaxUploadSessions) }},
+	{"max-upload-sessions-per-owner", "resumable uploads every owner may keep at once", func(cfg *Config) flag.Value { return (*int64Value)(&cfg.MaxUploadSessionsPerOwner) }},
+	{"variants", "comma separated resized variants as name=longer side", func(cfg *Config) flag.Value { return (*variantsValue)(&cfg.Variants) }},
+	{"shutdown-timeout", "how long uploads in flight may take to finish on shutdown", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.ShutdownTimeout) }},
+	{"tls-cert", "PEM certificate served over TLS, plain text if empty", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TLSCert) }},
+	{"tls-key", "PEM private key of the TLS certificate", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TLSKey) }},
+	{"tls-client-ca", "PEM bundle of the CAs client certificates must be signed by, no client certificates if empty", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TLSClientCA) }},
+	{"tls-reload-interval", "how often the TLS files are checked for changes", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.TLSReloadInterval) }},
+	{"auth-keys-file", `file of API keys, one "<key> <subject> [admin]" per line`, func(cfg *Config) flag.Value { return (*stringValue)(&cfg.AuthKeysFile) }},
+	{"auth-jwt-secret-file", "file with the secret HS256 tokens are signed with", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.AuthJWTSecretFile) }},
+	{"tracing-exporter", "where spans are exported to: " + strings.Join(tracing.Exporters(), ", "), func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TracingExporter) }},
+	{"tracing-file", "file the stdout exporter appends spans to, standard output if empty", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TracingFile) }},
+	{"tracing-otlp-endpoint", "host:port of the collector the otlp exporter sends spans to", func(cfg *Config) flag.Value { return (*stringValue)(&cfg.TracingOTLPEndpoint) }},
+	{"tracing-otlp-insecure", "send spans to the collector without TLS", func(cfg *Config) flag.Value { return (*boolValue)(&cfg.TracingOTLPInsecure) }},
+	{"log-level", "lowest level that is logged: " + strings.Join(logging.Levels(), ", "), func(cfg *Config) flag.Value { return (*stringValue)(&cfg.LogLevel) }},
+	{"log-format", "format of the log lines: " + strings.Join(logging.Formats(), ", "), func(cfg *Config) flag.Value { return (*stringValue)(&cfg.LogFormat) }},
+	{"health-check-interval", "how often the store is checked for the health status", func(cfg *Config) flag.Value { return (*durationValue)(&cfg.HealthCheckInterval) }},
+	{"reflection", "serve gRPC server reflection", func(cfg *Config) flag.Value { return (*boolValue)(&cfg.Reflection) }},
+}
+
+// Load builds the configuration from the command line arguments and the environment.
+// The file is given by the -config flag or the IMAGE_SERVER_CONFIG variable.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	configPath := flags.String("config", "", "YAML file with the settings, env "+envPrefix+"CONFIG")
+	for _, s := range settings {
+		flags.Var(s.value(Default()), s.name, fmt.Sprintf("%s, env %s", s.usage, envName(s.name)))
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		err := cfg.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(envName(s.name)); ok {
+			err := s.value(cfg).Set(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", envName(s.name), err)
+			}
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.name == f.Name {
+				// the flag has already been parsed into a copy, so this cannot fail
+				s.value(cfg).Set(f.Value.String())
+			}
+		}
+	})
+
+	cfg.StorageRoot, err = filepath.Abs(cfg.StorageRoot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve storage root: %w", err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	// maps are merged into by the decoder, the variants of the file replace the default ones instead
+	variants := cfg.Variants
+	cfg.Variants = nil
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	if cfg.Variants == nil {
+		cfg.Variants = variants
+	}
+	return nil
+}
+
+// Validate reports every setting that has an unusable value
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if _, _, err := net.SplitHostPort(cfg.Listen); err != nil {
+		problems = append(problems, fmt.Sprintf("listen: %v", err))
+	}
+	if cfg.HTTPListen != "" {
+		if _, _, err := net.SplitHostPort(cfg.HTTPListen); err != nil {
+			problems = append(problems, fmt.Sprintf("http-listen: %v", err))
+		}
+	}
+	if cfg.MetricsListen != "" {
+		if _, _, err := net.SplitHostPort(cfg.MetricsListen); err != nil {
+			problems = append(problems, fmt.Sprintf("metrics-listen: %v", err))
+		}
+	}
+	if cfg.StorageRoot == "" {
+		problems = append(problems, "storage-root: must not be empty")
+	}
+	if cfg.MaxReadConns <= 0 {
+		problems = append(problems, "max-read-conns: must be positive")
+	}
+	if cfg.MaxStreamConns <= 0 {
+		problems = append(problems, "max-stream-conns: must be positive")
+	}
+	if cfg.MaxImageSize <= 0 {
+		problems = append(problems, "max-image-size: must be positive")
+	}
+	// the size of an uploaded image is returned in a 32 bit field
+	if cfg.MaxImageSize > math.MaxUint32 {
+		problems = append(problems, fmt.Sprintf("max-image-size: must be at most %d bytes", uint32(math.MaxUint32)))
+	}
+	if cfg.MaxImagePixels <= 0 {
+		problems = append(problems, "max-image-pixels: must be positive")
+	}
+	if cfg.UploadByteBudget < cfg.MaxImageSize {
+		problems = append(problems, "upload-byte-budget: must be at least max-image-size")
+	}
+	if cfg.MaxQueueWait < 0 {
+		problems = append(problems, "max-queue-wait: must not be negative")
+	}
+
+	if len(cfg.AllowedTypes) == 0 {
+		problems = append(problems, "allowed-types: must not be empty")
+	}
+	known := services.ImageFormatNames()
+	for _, imageType := range cfg.AllowedTypes {
+		if !contains(known, imageType) {
+			problems = append(problems, fmt.Sprintf("allowed-types: unknown type %q, known are %s", imageType, strings.Join(known, ", ")))
+		}
+	}
+
+	if cfg.TrashRetention < 0 {
+		problems = append(problems, "trash-retention: must not be negative")
+	}
+	if cfg.UploadTimeout <= 0 {
+		problems = append(problems, "upload-timeout: must be positive")
+	}
+	if cfg.MaxUploadSessions <= 0 {
+		problems = append(problems, "max-upload-sessions: must be positive")
+	}
+	if cfg.MaxUploadSessionsPerOwner <= 0 {
+		problems = append(problems, "max-upload-sessions-per-owner: must be positive")
+	}
+	for name, maxSide := range cfg.Variants {
+		if name == "" || strings.ContainsAny(name, `/\.`) {
+			problems = append(problems, fmt.Sprintf("variants: invalid name %q", name))
+		}
+		if maxSide <= 0 {
+			problems = append(problems, fmt.Sprintf("variants: size of %s must be positive", name))
+		}
+	}
+	if cfg.ShutdownTimeout < 0 {
+		problems = append(problems, "shutdown-timeout: must not be negative")
+	}
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		problems = append(problems, "tls-cert, tls-key: must be set together")
+	}
+	if cfg.TLSClientCA != "" && cfg.TLSCert == "" {
+		problems = append(problems, "tls-client-ca: needs tls-cert and tls-key")
+	}
+	if cfg.TLSReloadInterval <= 0 {
+		problems = append(problems, "tls-reload-interval: must be positive")
+	}
+
+	if !contains(tracing.Exporters(), cfg.TracingExporter) {
+		problems = append(problems, fmt.Sprintf("tracing-exporter: unknown exporter %q, known are %s", cfg.TracingExporter, strings.Join(tracing.Exporters(), ", ")))
+	}
+	if cfg.TracingExporter == tracing.ExporterOTLP {
+		if _, _, err := net.SplitHostPort(cfg.TracingOTLPEndpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("tracing-otlp-endpoint: %v", err))
+		}
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("log-level: %v", err))
+	}
+	if !contains(logging.Formats(), cfg.LogFormat) {
+		problems = append(problems, fmt.Sprintf("log-format: unknown format %q, known are %s", cfg.LogFormat, strings.Join(logging.Formats(), ", ")))
+	}
+
+	if cfg.HealthCheckInterval <= 0 {
+		problems = append(problems, "health-check-interval: must be positive")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// String lists every setting with its effective value
+func (cfg *Config) String() string {
+	var b strings.Builder
+	for _, s := range settings {
+		fmt.Fprintf(&b, "  %s: %s\n", s.name, s.value(cfg))
+	}
+	return b.String()
+}
+
+// LogValue lists every setting with its effective value as attributes of the log line
+func (cfg *Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		attrs = append(attrs, slog.String(s.name, s.value(cfg).String()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookupEnv over the given variables
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+max_read_conns: 5
+max_stream_conns: 7
+max_image_size: 1MiB
+trash_retention: 1h
+variants:
+  small: 64
+`)
+
+	cfg, err := Load(
+		[]string{"-max-read-conns=8"},
+		env(map[string]string{
+			"IMAGE_SERVER_CONFIG":          path,
+			"IMAGE_SERVER_MAX_READ_CONNS":  "6",
+			"IMAGE_SERVER_TRASH_RETENTION": "2h",
+			"IMAGE_SERVER_MAX_QUEUE_WAIT":  "3s",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	defaults := Default()
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"flag over env and file", cfg.MaxReadConns, int64(8)},
+		{"env over file", cfg.TrashRetention, 2 * time.Hour},
+		{"env over default", cfg.MaxQueueWait, 3 * time.Second},
+		{"file over default", cfg.MaxStreamConns, int64(7)},
+		{"byte size in the file", cfg.MaxImageSize, ByteSize(1 << 20)},
+		{"default", cfg.Listen, defaults.Listen},
+		{"variants of the file replace the defaults", cfg.Variants, map[string]int{"small": 64}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if !filepath.IsAbs(cfg.StorageRoot) {
+		t.Errorf("storage root %q is not absolute", cfg.StorageRoot)
+	}
+}
+
+func TestLoadConfigFlagOverEnv(t *testing.T) {
+	fromFlag := writeConfig(t, "max_stream_conns: 3\n")
+	fromEnv := writeConfig(t, "max_stream_conns: 4\n")
+
+	cfg, err := Load([]string{"-config", fromFlag}, env(map[string]string{"IMAGE_SERVER_CONFIG": fromEnv}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.MaxStreamConns != 3 {
+		t.Errorf("got max-stream-conns %d, want 3 of the file given by the flag", cfg.MaxStreamConns)
+	}
+}
+
+func TestLoadVariantsWithoutFile(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Variants, Default().Variants) {
+		t.Errorf("got variants %v, want the defaults", cfg.Variants)
+	}
+
+	// a file without variants keeps the defaults, the flag replaces them
+	path := writeConfig(t, "max_read_conns: 5\n")
+	cfg, err = Load([]string{"-variants", "tiny=32"}, env(map[string]string{"IMAGE_SERVER_CONFIG": path}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Variants, map[string]int{"tiny": 32}) {
+		t.Errorf("got variants %v, want tiny=32 of the flag", cfg.Variants)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     string
+		wantText []string
+	}{
+		{
+			name:     "unknown flag",
+			args:     []string{"-no-such-setting=1"},
+			wantText: []string{"no-such-setting"},
+		},
+		{
+			name:     "invalid env",
+			env:      map[string]string{"IMAGE_SERVER_MAX_READ_CONNS": "many"},
+			wantText: []string{"invalid IMAGE_SERVER_MAX_READ_CONNS", `invalid number "many"`},
+		},
+		{
+			name:     "unknown field in the file",
+			file:     "max_reads: 5\n",
+			wantText: []string{"cannot parse config file", "max_reads"},
+		},
+		{
+			name:     "invalid byte size in the file",
+			file:     "max_image_size: huge\n",
+			wantText: []string{"cannot parse config file", `invalid size "huge"`},
+		},
+		{
+			name: "every problem is reported",
+			args: []string{
+				"-listen=nowhere",
+				"-max-read-conns=0",
+				"-max-image-size=5GiB",
+				"-upload-byte-budget=1KiB",
+				"-allowed-types=png,bmp",
+				"-variants=a/b=10,small=0",
+				"-tls-cert=cert.pem",
+				"-log-level=loud",
+			},
+			env: map[string]string{"IMAGE_SERVER_MAX_QUEUE_WAIT": "-1s"},
+			wantText: []string{
+				"invalid config:",
+				"listen: address nowhere: missing port in address",
+				"max-read-conns: must be positive",
+				"max-image-size: must be at most 4294967295 bytes",
+				"upload-byte-budget: must be at least max-image-size",
+				"max-queue-wait: must not be negative",
+				`allowed-types: unknown type "bmp"`,
+				`variants: invalid name "a/b"`,
+				"variants: size of small must be positive",
+				"tls-cert, tls-key: must be set together",
+				"log-level:",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := tt.env
+			if tt.file != "" {
+				vars = map[string]string{"IMAGE_SERVER_CONFIG": writeConfig(t, tt.file)}
+			}
+			_, err := Load(tt.args, env(vars))
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			for _, want := range tt.wantText {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		value      string
+		want       ByteSize
+		wantString string
+		wantErr    bool
+	}{
+		{value: "1024", want: 1024, wantString: "1KiB"},
+		{value: "1000", want: 1000, wantString: "1000B"},
+		{value: "1B", want: 1, wantString: "1B"},
+		{value: "64KiB", want: 64 << 10, wantString: "64KiB"},
+		{value: " 256 MiB ", want: 256 << 20, wantString: "256MiB"},
+		{value: "2GiB", want: 2 << 30, wantString: "2GiB"},
+		{value: "0", want: 0, wantString: "0"},
+		{value: "9223372036854775807", want: 1<<63 - 1, wantString: "9223372036854775807B"},
+		{value: "8589934591GiB", want: 8589934591 << 30, wantString: "8589934591GiB"},
+		{value: "8589934592GiB", wantErr: true},
+		{value: "-8589934593GiB", wantErr: true},
+		{value: "9223372036854775808", wantErr: true},
+		{value: "1TiB", wantErr: true},
+		{value: "1.5MiB", wantErr: true},
+		{value: "MiB", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var size ByteSize
+			err := size.Set(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %d, want an error", size)
+				} else if !strings.Contains(err.Error(), strings.TrimSpace(tt.value)) {
+					t.Errorf("error %q does not report the value", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if size != tt.want {
+				t.Errorf("got %d, want %d", size, tt.want)
+			}
+			if s := size.String(); s != tt.wantString {
+				t.Errorf("String() = %q, want %q", s, tt.wantString)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the values below implement flag.Value over the fields of Config,
+// so that flags and environment variables are parsed the same way
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+type int64Value int64
+
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+func (v *int64Value) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*v), 10)
+}
+
+// boolValue is a flag that can be given without a value, such as -tracing-otlp-insecure
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string {
+	if v == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*v))
+}
+
+// IsBoolFlag lets the flag package accept the flag without a value
+func (v *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string {
+	if v == nil {
+		return "0s"
+	}
+	return time.Duration(*v).String()
+}
+
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+
+func (v *listValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ",")
+}
+
+// variantsValue is written as name=size pairs, such as thumbnail=128,preview=512
+type variantsValue map[string]int
+
+func (v *variantsValue) Set(s string) error {
+	variants := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, size, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid variant %q, expected name=size", pair)
+		}
+		maxSide, err := strconv.Atoi(size)
+		if err != nil {
+			return fmt.Errorf("invalid size of variant %s: %q", name, size)
+		}
+		variants[strings.TrimSpace(name)] = maxSide
+	}
+	*v = variants
+	return nil
+}
+
+func (v *variantsValue) String() string {
+	if v == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*v))
+	for name := range *v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, (*v)[name]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ByteSize is a number of bytes, written as a plain number or with a KiB, MiB or GiB suffix
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+func (v *ByteSize) Set(value string) error {
+	s := strings.TrimSpace(value)
+	unit := int64(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	if n > math.MaxInt64/unit || n < math.MinInt64/unit {
+		return fmt.Errorf("size %q is too large", value)
+	}
+	*v = ByteSize(n * unit)
+	return nil
+}
+
+func (v *ByteSize) String() string {
+	if v == nil {
+		return "0"
+	}
+	for _, u := range byteSizeUnits {
+		if int64(*v) != 0 && int64(*v)%u.size == 0 {
+			return fmt.Sprintf("%d%s", int64(*v)/u.size, u.suffix)
+		}
+	}
+	return "0"
+}
+
+// UnmarshalText lets the config file use the same notation as the flags
+func (v *ByteSize) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
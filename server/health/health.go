@@ -0,0 +1,127 @@
+// Package health serves the standard grpc.health.v1 service,
+// with a status that follows a check of what the server depends on.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Checker runs a check periodically and reports the services as serving while it passes
+type Checker struct {
+	server   *grpchealth.Server
+	check    func() error
+	services []string
+	interval time.Duration
+	healthy  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewChecker runs the check once right away and then every interval.
+// The status applies to the named services and to the server as a whole, the empty service name.
+func NewChecker(check func() error, interval time.Duration, services ...string) *Checker {
+	checker := &Checker{
+		server:   grpchealth.NewServer(),
+		check:    check,
+		services: append([]string{""}, services...),
+		interval: interval,
+		// the first failure is logged too
+		healthy: true,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	checker.update()
+	go checker.run()
+	return checker
+}
+
+// Server returns the health service to register with the gRPC server
+func (checker *Checker) Server() healthpb.HealthServer {
+	return &healthServer{checker.server, checker.stop}
+}
+
+// Shutdown stops the checks and reports every service as not serving from now on,
+// so that new requests go elsewhere while the ones in flight finish
+func (checker *Checker) Shutdown() {
+	close(checker.stop)
+	<-checker.done
+	checker.server.Shutdown()
+}
+
+func (checker *Checker) run() {
+	defer close(checker.done)
+
+	ticker := time.NewTicker(checker.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-checker.stop:
+			return
+		case <-ticker.C:
+			checker.update()
+		}
+	}
+}
+
+// healthServer ends the watches once they are told that the server is shutting down,
+// otherwise they would hold up the graceful stop until the shutdown timeout
+type healthServer struct {
+	*grpchealth.Server
+	stop chan struct{}
+}
+
+func (server *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	return server.Server.Watch(req, &watchStream{stream, ctx, cancel, server.stop})
+}
+
+type watchStream struct {
+	healthpb.Health_WatchServer
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+}
+
+func (stream *watchStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *watchStream) Send(res *healthpb.HealthCheckResponse) error {
+	err := stream.Health_WatchServer.Send(res)
+	select {
+	case <-stream.stop:
+		if res.GetStatus() == healthpb.HealthCheckResponse_NOT_SERVING {
+			stream.cancel()
+		}
+	default:
+	}
+	return err
+}
+
+func (checker *Checker) update() {
+	err := checker.check()
+	status := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	switch {
+	case err != nil && checker.healthy:
+		slog.Error("health check failed, not serving", "error", err)
+	case err == nil && !checker.healthy:
+		slog.Info("health check passed, serving")
+	}
+	checker.healthy = err == nil
+
+	for _, service := range checker.services {
+		checker.server.SetServingStatus(service, status)
+	}
+}
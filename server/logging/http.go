@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the response header the request id of an HTTP request is returned in
+const RequestIDHeader = "X-Request-Id"
+
+// HTTPHandler gives the HTTP requests a request id and logs how they end, like the interceptors do for calls
+func HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := uuid.NewString()
+		ctx := NewContext(r.Context(), requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		writer := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(writer, r.WithContext(ctx))
+		logRequest(r.WithContext(ctx), writer, start)
+	})
+}
+
+// logRequest logs the outcome of the request, failures that are the server's fault as errors
+func logRequest(r *http.Request, writer *statusWriter, start time.Time) {
+	code := writer.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case code >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case code >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	slog.LogAttrs(r.Context(), level, "finished request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", code),
+		slog.Int64("bytes", writer.written),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// statusWriter records the status code and the size of the response
+type statusWriter struct {
+	http.ResponseWriter
+	code    int
+	written int64
+}
+
+func (writer *statusWriter) WriteHeader(code int) {
+	if writer.code == 0 {
+		writer.code = code
+	}
+	writer.ResponseWriter.WriteHeader(code)
+}
+
+func (writer *statusWriter) Write(p []byte) (int, error) {
+	if writer.code == 0 {
+		writer.code = http.StatusOK
+	}
+	n, err := writer.ResponseWriter.Write(p)
+	writer.written += int64(n)
+	return n, err
+}
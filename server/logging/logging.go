@@ -0,0 +1,84 @@
+// Package logging sets up the structured logger of the server and gives every call a request id
+// that is attached to the lines logged for the call and returned to the client in the trailers,
+// or in the X-Request-Id header for HTTP requests.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the formats log lines can be written in
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats returns the names of the formats log lines can be written in
+func Formats() []string {
+	return []string{FormatText, FormatJSON}
+}
+
+// Levels returns the names of the levels that can be logged from
+func Levels() []string {
+	return []string{"debug", "info", "warn", "error"}
+}
+
+// ParseLevel returns the level of one of Levels
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	if err != nil || !strings.EqualFold(level.String(), name) {
+		return 0, fmt.Errorf("unknown log level %q, known are %s", name, strings.Join(Levels(), ", "))
+	}
+	return level, nil
+}
+
+// New returns a logger that writes the lines of the level and above to w in the format.
+// The lines logged with a call's context carry its request id and trace id.
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	minLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: minLevel}
+	var handler slog.Handler
+	switch format {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, known are %s", format, strings.Join(Formats(), ", "))
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the ids in the context to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID, ok := RequestID(ctx); ok {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey is the trailer the request id is returned in
+const RequestIDKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// NewContext returns a context that carries the request id
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request id of the call the context belongs to
+func RequestID(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+// UnaryInterceptor gives the unary calls a request id and logs how they end.
+// It should come first so that the lines of the other interceptors carry the id too.
+func UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		requestID := uuid.NewString()
+		ctx = NewContext(ctx, requestID)
+		res, err := handler(ctx, req)
+		grpc.SetTrailer(ctx, metadata.Pairs(RequestIDKey, requestID))
+		logCall(ctx, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamInterceptor gives the streaming calls a request id and logs how they end.
+// It should come first so that the lines of the other interceptors carry the id too.
+func StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		requestID := uuid.NewString()
+		ctx := NewContext(stream.Context(), requestID)
+		err := handler(srv, &requestStream{stream, ctx})
+		stream.SetTrailer(metadata.Pairs(RequestIDKey, requestID))
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logCall logs the outcome of the call, failures that are the server's fault as errors
+func logCall(ctx context.Context, fullMethod string, start time.Time, err error) {
+	level := slog.LevelInfo
+	switch status.Code(err) {
+	case codes.OK:
+		// probes come every few seconds and would drown the calls of the clients
+		if strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+			level = slog.LevelDebug
+		}
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", path.Base(fullMethod)),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	slog.LogAttrs(ctx, level, "finished call", attrs...)
+}
+
+// requestStream hands the context with the request id to stream handlers
+type requestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *requestStream) Context() context.Context {
+	return stream.ctx
+}
@@ -1,38 +1,299 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/navruz-rakhimov/tages-project/protos"
+	"github.com/navruz-rakhimov/tages-project/server/auth"
+	"github.com/navruz-rakhimov/tages-project/server/certs"
+	"github.com/navruz-rakhimov/tages-project/server/config"
+	"github.com/navruz-rakhimov/tages-project/server/health"
+	"github.com/navruz-rakhimov/tages-project/server/logging"
+	"github.com/navruz-rakhimov/tages-project/server/metrics"
 	"github.com/navruz-rakhimov/tages-project/server/services"
+	"github.com/navruz-rakhimov/tages-project/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
-const (
-	port           = ":5001"
-	maxReadConns   = 100
-	maxStreamConns = 10
-)
+// tracingFlushTimeout bounds how long the spans that are left may take to export on exit
+const tracingFlushTimeout = 5 * time.Second
+
+// httpReadHeaderTimeout bounds how long clients of the REST gateway may take to send the headers of a request
+const httpReadHeaderTimeout = 10 * time.Second
 
 func main() {
-	currentDir, _ := os.Getwd()
-	tmpDir := currentDir + "\\server\\tmp"
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// the lines of the log package go through the logger as well
+	slog.SetDefault(logger)
+	slog.Info("effective config", "config", cfg)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "image-server", tracing.Options{
+		Exporter:     cfg.TracingExporter,
+		File:         cfg.TracingFile,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
 
-	imageStore := services.NewDiskImageStore(tmpDir)
-	imageServer := services.NewImageServer(imageStore, maxReadConns, maxStreamConns)
+	openImageStore := services.NewDiskImageStore
+	if cfg.StorageDedup {
+		openImageStore = services.NewContentImageStore
+	}
+	imageStore, err := openImageStore(cfg.StorageRoot, cfg.TrashRetention, cfg.Variants, cfg.MaxImagePixels)
+	if err != nil {
+		fatal("failed to open image store", err)
+	}
 
-	lis, err := net.Listen("tcp", port)
+	uploadSessions, err := services.NewUploadSessions(filepath.Join(cfg.StorageRoot, ".sessions"), cfg.UploadTimeout, int(cfg.MaxUploadSessions), int(cfg.MaxUploadSessionsPerOwner))
 	if err != nil {
-		log.Fatalf("failed to listen %v", err)
+		fatal("failed to set up upload sessions", err)
+	}
+
+	imageServer := services.NewImageServer(imageStore, uploadSessions, services.ImageServerLimits{
+		MaxReadConns:     cfg.MaxReadConns,
+		MaxStreamConns:   cfg.MaxStreamConns,
+		MaxImageSize:     int64(cfg.MaxImageSize),
+		MaxImagePixels:   cfg.MaxImagePixels,
+		MaxQueueWait:     cfg.MaxQueueWait,
+		UploadByteBudget: int64(cfg.UploadByteBudget),
+		AllowedFormats:   cfg.AllowedTypes,
+	})
+
+	lis, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		fatal("failed to listen", err)
+	}
+
+	var serverOptions []grpc.ServerOption
+	var tlsConfig *tls.Config
+	if cfg.TLSCert != "" {
+		reloader, err := certs.NewReloader(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA, cfg.TLSReloadInterval)
+		if err != nil {
+			fatal("failed to set up TLS", err)
+		}
+		defer reloader.Close()
+
+		tlsConfig = reloader.TLSConfig()
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		if reloader.RequiresClientCert() {
+			slog.Info("serving mutual TLS, clients need a certificate")
+		} else {
+			slog.Info("serving TLS")
+		}
+	} else {
+		slog.Warn("serving plain text, set tls-cert and tls-key to use TLS")
+	}
+
+	registry := metrics.NewRegistry()
+	imageServer.RegisterMetrics(registry)
+	imageStore.RegisterMetrics(registry)
+	rpcMetrics := metrics.NewRPCMetrics(registry)
+
+	// the request id comes first so that every line logged for a call carries it,
+	// then the metrics so that calls rejected by the later interceptors are counted
+	unaryInterceptors := []grpc.UnaryServerInterceptor{logging.UnaryInterceptor(), rpcMetrics.UnaryServerInterceptor()}
+	streamInterceptors := []grpc.StreamServerInterceptor{logging.StreamInterceptor(), rpcMetrics.StreamServerInterceptor()}
+
+	var authenticators []auth.Authenticator
+	var interceptor *auth.Interceptor
+	if cfg.AuthKeysFile != "" {
+		apiKeys, err := auth.LoadAPIKeys(cfg.AuthKeysFile)
+		if err != nil {
+			fatal("failed to set up authentication", err)
+		}
+		slog.Info("loaded API keys", "keys", apiKeys.Len())
+		authenticators = append(authenticators, apiKeys)
+	}
+	if cfg.AuthJWTSecretFile != "" {
+		verifier, err := auth.LoadJWTVerifier(cfg.AuthJWTSecretFile)
+		if err != nil {
+			fatal("failed to set up authentication", err)
+		}
+		authenticators = append(authenticators, verifier)
+	}
+	if len(authenticators) > 0 {
+		interceptor = auth.NewInterceptor(authenticators...)
+		// probes of an orchestrator carry no token
+		interceptor.AllowUnauthenticated(healthpb.Health_ServiceDesc.ServiceName)
+		unaryInterceptors = append(unaryInterceptors, interceptor.Unary())
+		streamInterceptors = append(streamInterceptors, interceptor.Stream())
+	} else {
+		slog.Warn("authentication is disabled, set auth-keys-file or auth-jwt-secret-file to enable it")
 	}
+	serverOptions = append(serverOptions,
+		// the span of a call starts before the interceptors run and joins the trace of the client
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
 
-	s := grpc.NewServer()
+	var metricsServer *http.Server
+	if cfg.MetricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(registry))
+		metricsServer = &http.Server{Addr: cfg.MetricsListen, Handler: mux, ReadHeaderTimeout: httpReadHeaderTimeout}
+		go func() {
+			err := metricsServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("failed to serve metrics", err)
+			}
+		}()
+		slog.Info("serving metrics", "address", cfg.MetricsListen, "path", "/metrics")
+	}
+
+	s := grpc.NewServer(serverOptions...)
 	protos.RegisterImageServiceServer(s, imageServer)
-	log.Printf("Sever listening at %v", lis.Addr())
+	// every method shows up in the metrics before it is first called
+	rpcMetrics.InitializeMetrics(s)
+
+	healthChecker := health.NewChecker(imageStore.Check, cfg.HealthCheckInterval, protos.ImageService_ServiceDesc.ServiceName)
+	healthpb.RegisterHealthServer(s, healthChecker.Server())
+	if cfg.Reflection {
+		reflection.Register(s)
+		slog.Info("serving reflection")
+	}
+	slog.Info("server listening", "address", lis.Addr().String())
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- s.Serve(lis)
+	}()
+
+	var httpServer *http.Server
+	if cfg.HTTPListen != "" {
+		httpLis, err := net.Listen("tcp", cfg.HTTPListen)
+		if err != nil {
+			fatal("failed to listen for the REST gateway", err)
+		}
+		// the request id comes first so that the lines logged for a request carry the trace id too
+		handler := logging.HTTPHandler(services.NewRESTHandler(imageServer, interceptor))
+		httpServer = &http.Server{
+			Handler:           otelhttp.NewHandler(handler, "rest"),
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: httpReadHeaderTimeout,
+		}
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				// the certificate comes from the TLS config
+				err = httpServer.ServeTLS(httpLis, "", "")
+			} else {
+				err = httpServer.Serve(httpLis)
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}()
+		slog.Info("REST gateway listening", "address", httpLis.Addr().String())
+	}
 
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		fatal("failed to serve", err)
+	case sig := <-signals:
+		slog.Info("shutting down", "signal", sig.String())
+	}
+	signal.Stop(signals)
+
+	// probes see the server going away before it stops taking requests
+	healthChecker.Shutdown()
+	shutdown(s, httpServer, imageServer, cfg.ShutdownTimeout)
+	if metricsServer != nil {
+		metricsServer.Close()
 	}
+
+	if err := uploadSessions.Close(); err != nil {
+		slog.Error("failed to clean up upload sessions", "error", err)
+	}
+	if err := imageStore.Close(); err != nil {
+		slog.Error("failed to close image store", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush spans", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+// shutdown stops accepting requests and gives the ones in flight until the timeout to finish,
+// the rest are cancelled and their partial uploads are dropped
+func shutdown(s *grpc.Server, httpServer *http.Server, imageServer *services.ImageServer, timeout time.Duration) {
+	imageServer.Drain()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// the REST requests drain alongside the calls
+	httpStopped := make(chan error, 1)
+	if httpServer != nil {
+		go func() {
+			httpStopped <- httpServer.Shutdown(ctx)
+		}()
+	} else {
+		httpStopped <- nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("requests still running, cancelling them", "timeout", timeout)
+		s.Stop()
+		<-stopped
+	}
+	if err := <-httpStopped; err != nil {
+		slog.Warn("REST requests still running, cancelling them", "timeout", timeout)
+		httpServer.Close()
+	}
+
+	// Stop does not wait for the handlers, the cancelled uploads still have to clean up
+	imageServer.Wait(ctx)
+}
+
+// fatal logs the error that keeps the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
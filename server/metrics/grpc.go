@@ -0,0 +1,39 @@
+// Package metrics sets up the Prometheus registry of the image server and the metrics of its calls.
+package metrics
+
+import (
+	"net/http"
+
+	grpcprom "github.com/grpc-ecosystem/go-grpc-middleware/providers/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// latencyBuckets reach into minutes, streams of large images take that long
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// NewRegistry returns a registry with the metrics of the Go runtime and of the process
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// Handler serves the metrics of the registry in the Prometheus exposition format
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// NewRPCMetrics registers the metrics of the calls with the registry, counted by method and status code and timed.
+// Its interceptors should come first so that rejected calls are counted too.
+func NewRPCMetrics(registerer prometheus.Registerer) *grpcprom.ServerMetrics {
+	rpcMetrics := grpcprom.NewServerMetrics(
+		grpcprom.WithServerHandlingTimeHistogram(grpcprom.WithHistogramBuckets(latencyBuckets)),
+	)
+	registerer.MustRegister(rpcMetrics)
+	return rpcMetrics
+}
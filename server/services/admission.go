@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/navruz-rakhimov/tages-project/protos"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// admission limits how much of a resource the requests of one kind hold at once,
+// either a slot per request or a weight such as the bytes of the uploads in flight.
+// The others queue until their context ends or the queue wait runs out.
+type admission struct {
+	name         string
+	unit         string
+	limit        int64
+	maxQueueWait time.Duration
+	sem          *semaphore.Weighted
+
+	// running is the weight in use, the number of requests for slots
+	running   atomic.Int64
+	waiting   atomic.Int64
+	admitted  atomic.Int64
+	rejected  atomic.Int64
+	totalWait atomic.Int64
+}
+
+// newAdmission returns an admission with limit slots, each request takes one
+func newAdmission(name string, limit int64, maxQueueWait time.Duration) *admission {
+	return newWeightedAdmission(name, "slots", limit, maxQueueWait)
+}
+
+// newWeightedAdmission returns an admission with a budget of limit, counted in unit
+func newWeightedAdmission(name string, unit string, limit int64, maxQueueWait time.Duration) *admission {
+	return &admission{
+		name:         name,
+		unit:         unit,
+		limit:        limit,
+		maxQueueWait: maxQueueWait,
+		sem:          semaphore.NewWeighted(limit),
+	}
+}
+
+// acquire waits for a slot and returns a status error if none frees up in time.
+// A request that is admitted must call release once it is done.
+func (admission *admission) acquire(ctx context.Context) error {
+	return admission.acquireWeight(ctx, 1)
+}
+
+func (admission *admission) release() {
+	admission.releaseWeight(1)
+}
+
+// acquireWeight waits until the weight fits into the budget and returns a status error if it does not in time
+func (admission *admission) acquireWeight(ctx context.Context, weight int64) error {
+	start := time.Now()
+
+	// free weight is taken even if the queue wait is zero
+	if !admission.sem.TryAcquire(weight) {
+		admission.waiting.Add(1)
+		waitCtx, cancel := context.WithTimeout(ctx, admission.maxQueueWait)
+		err := admission.sem.Acquire(waitCtx, weight)
+		cancel()
+		admission.waiting.Add(-1)
+		if err != nil {
+			return admission.reject(ctx, start)
+		}
+	}
+
+	admission.admit(weight, start)
+	return nil
+}
+
+func (admission *admission) admit(weight int64, start time.Time) {
+	admission.running.Add(weight)
+	admission.admitted.Add(1)
+	admission.totalWait.Add(int64(time.Since(start)))
+}
+
+func (admission *admission) reject(ctx context.Context, start time.Time) error {
+	admission.rejected.Add(1)
+	admission.totalWait.Add(int64(time.Since(start)))
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return admission.exhausted(ctx)
+}
+
+func (admission *admission) releaseWeight(weight int64) {
+	admission.running.Add(-weight)
+	admission.sem.Release(weight)
+}
+
+// exhausted tells the client when to try again, both as retry info in the status
+// and as a retry-after header in seconds for clients that only look at metadata
+func (admission *admission) exhausted(ctx context.Context) error {
+	retryAfter := admission.maxQueueWait
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)))
+
+	st := status.Newf(codes.ResourceExhausted, "cannot admit %s request: all %d %s are in use, retry in %ds", admission.name, admission.limit, admission.unit, seconds)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func (admission *admission) stats() *pb.AdmissionStats {
+	return &pb.AdmissionStats{
+		Name:           admission.name,
+		Limit:          admission.limit,
+		Running:        admission.running.Load(),
+		Waiting:        admission.waiting.Load(),
+		Admitted:       admission.admitted.Load(),
+		Rejected:       admission.rejected.Load(),
+		TotalWaitMs:    time.Duration(admission.totalWait.Load()).Milliseconds(),
+		MaxQueueWaitMs: admission.maxQueueWait.Milliseconds(),
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	pb "github.com/navruz-rakhimov/tages-project/protos"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// uploadReservation holds the bytes of an upload from the byte budget.
+// The declared size of the upload, or the largest one it may reach if it has none, is taken when it is admitted,
+// so that the uploads in flight never wait for each other and all run out of time together.
+type uploadReservation struct {
+	budget   *admission
+	reserved int64
+	size     int64
+	maxSize  int64
+}
+
+// reserve takes maxSize bytes from the budget before any of the upload is received.
+// It returns a status error if the budget has no room for them in time.
+func (reservation *uploadReservation) reserve(ctx context.Context) error {
+	err := reservation.budget.acquireWeight(ctx, reservation.maxSize)
+	if err != nil {
+		return err
+	}
+	reservation.reserved = reservation.maxSize
+	return nil
+}
+
+// add counts size more bytes of the upload, it returns a status error if the image gets too large
+func (reservation *uploadReservation) add(size int64) error {
+	if reservation.size+size > reservation.maxSize {
+		return status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", reservation.size+size, reservation.maxSize)
+	}
+	reservation.size += size
+	return nil
+}
+
+// release gives back the bytes taken from the budget
+func (reservation *uploadReservation) release() {
+	reservation.budget.releaseWeight(reservation.reserved)
+	reservation.reserved = 0
+}
+
+// budgetReader counts the bytes read from r against the reservation of the upload.
+// The status error that stopped it is kept in err.
+type budgetReader struct {
+	r           io.Reader
+	reservation uploadReservation
+	err         error
+}
+
+func (reader *budgetReader) Read(p []byte) (int, error) {
+	if reader.err != nil {
+		return 0, reader.err
+	}
+
+	n, err := reader.r.Read(p)
+	if n > 0 {
+		if reserveErr := reader.reservation.add(int64(n)); reserveErr != nil {
+			reader.err = reserveErr
+			return 0, reader.err
+		}
+	}
+	if err != nil && err != io.EOF {
+		if _, ok := status.FromError(err); !ok {
+			err = receiveError("image data", err)
+		}
+		reader.err = err
+		return n, reader.err
+	}
+	return n, err
+}
+
+// imageChunkReader turns the chunks of an upload stream into an io.Reader.
+// The status error that stopped the stream is kept in err.
+// Receiving the chunks is traced in a span that ends with the stream.
+type imageChunkReader struct {
+	stream  pb.ImageService_UploadImageServer
+	metrics *uploadMetrics
+	chunk   []byte
+	size    int64
+	err     error
+
+	span     trace.Span
+	chunks   int
+	recvTime time.Duration
+}
+
+func newImageChunkReader(stream pb.ImageService_UploadImageServer, metrics *uploadMetrics) *imageChunkReader {
+	_, span := tracer.Start(stream.Context(), "receive chunks")
+	return &imageChunkReader{
+		stream:  stream,
+		metrics: metrics,
+		span:    span,
+	}
+}
+
+// endSpan ends the span of the chunk receipt, once
+func (reader *imageChunkReader) endSpan(err error) {
+	if reader.span == nil {
+		return
+	}
+	reader.span.SetAttributes(
+		attribute.Int("upload.chunks", reader.chunks),
+		attribute.Int64("upload.bytes", reader.size),
+		attribute.Float64("upload.recv_seconds", reader.recvTime.Seconds()),
+	)
+	endSpan(reader.span, err)
+	reader.span = nil
+}
+
+func (reader *imageChunkReader) Read(p []byte) (int, error) {
+	if reader.err != nil {
+		return 0, reader.err
+	}
+
+	for len(reader.chunk) == 0 {
+		start := time.Now()
+		req, err := reader.stream.Recv()
+		reader.recvTime += time.Since(start)
+		if err == io.EOF {
+			slog.DebugContext(reader.stream.Context(), "received all chunks", "chunks", reader.chunks, "size", reader.size)
+			reader.endSpan(nil)
+			return 0, io.EOF
+		}
+		if err != nil {
+			reader.err = receiveError("chunk data", err)
+			reader.endSpan(reader.err)
+			return 0, reader.err
+		}
+		reader.chunks++
+
+		chunk := req.GetChunkData()
+		slog.DebugContext(reader.stream.Context(), "received chunk", "size", len(chunk))
+		reader.metrics.observeChunk(len(chunk))
+		reader.size += int64(len(chunk))
+		reader.chunk = chunk
+	}
+
+	n := copy(p, reader.chunk)
+	reader.chunk = reader.chunk[n:]
+	return n, nil
+}
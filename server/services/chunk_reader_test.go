@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUploadReservationTakesMaxSizeAtAdmission(t *testing.T) {
+	budget := newWeightedAdmission("upload bytes", "bytes", 100, 50*time.Millisecond)
+	ctx := context.Background()
+
+	first := uploadReservation{budget: budget, maxSize: 60}
+	if err := first.reserve(ctx); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if err := first.add(60); err != nil {
+		t.Errorf("add up to maxSize: %v", err)
+	}
+	if err := first.add(1); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("add past maxSize: got %v, want InvalidArgument", err)
+	}
+
+	// the second upload is turned away before it receives anything, the first one keeps its bytes
+	second := uploadReservation{budget: budget, maxSize: 60}
+	if err := second.reserve(ctx); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("reserve past the budget: got %v, want ResourceExhausted", err)
+	}
+	second.release()
+
+	first.release()
+	if err := second.reserve(ctx); err != nil {
+		t.Errorf("reserve after release: %v", err)
+	}
+	second.release()
+	if running := budget.running.Load(); running != 0 {
+		t.Errorf("budget holds %d bytes after all releases", running)
+	}
+}
+
+func TestUploadReservationLargeOnesAreNotStarved(t *testing.T) {
+	budget := newWeightedAdmission("upload bytes", "bytes", 100, time.Second)
+	ctx := context.Background()
+
+	small := uploadReservation{budget: budget, maxSize: 10}
+	if err := small.reserve(ctx); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	large := uploadReservation{budget: budget, maxSize: 100}
+	reserved := make(chan error)
+	go func() {
+		reserved <- large.reserve(ctx)
+	}()
+	for budget.waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// a small upload that comes later queues behind the large one instead of taking the free bytes
+	later := uploadReservation{budget: budget, maxSize: 10}
+	laterCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := later.reserve(laterCtx); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("reserve behind a waiting large one: got %v, want DeadlineExceeded", err)
+	}
+
+	small.release()
+	if err := <-reserved; err != nil {
+		t.Fatalf("large reserve after release: %v", err)
+	}
+	large.release()
+}
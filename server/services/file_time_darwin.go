@@ -0,0 +1,19 @@
+//go:build darwin
+
+package services
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileBirthTime returns the creation time APFS and HFS+ keep for every file
+func fileBirthTime(_ string, fileInfo os.FileInfo) (time.Time, bool) {
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(stat.Birthtimespec.Unix()), true
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package services
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileBirthTime returns the creation time of the file if the kernel and the file system report it
+func fileBirthTime(path string, _ os.FileInfo) (time.Time, bool) {
+	var stat unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_STATX_SYNC_AS_STAT, unix.STATX_BTIME, &stat)
+	if err != nil || stat.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec)), true
+}
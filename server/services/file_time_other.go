@@ -0,0 +1,13 @@
+//go:build !linux && !windows && !darwin
+
+package services
+
+import (
+	"os"
+	"time"
+)
+
+// fileBirthTime reports that the creation time is unknown on this platform
+func fileBirthTime(_ string, _ os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package services
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileBirthTime returns the creation time NTFS keeps for every file
+func fileBirthTime(_ string, fileInfo os.FileInfo) (time.Time, bool) {
+	fileTime, ok := fileInfo.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, fileTime.CreationTime.Nanoseconds()), true
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/navruz-rakhimov/tages-project/server/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// caller returns the owner recorded for the images the caller uploads and whether it may access every image.
+// Without authentication there is no identity, the owner is empty and everyone is an admin.
+func caller(ctx context.Context) (string, bool) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", true
+	}
+	return identity.Subject, identity.Admin
+}
+
+func isAuthenticated(ctx context.Context) bool {
+	_, ok := auth.FromContext(ctx)
+	return ok
+}
+
+// canAccess reports whether the caller may see and change an image of the owner
+func canAccess(ctx context.Context, owner string) bool {
+	subject, admin := caller(ctx)
+	return admin || subject == owner
+}
+
+// checkAccess returns a status error unless the caller may access the image.
+// Images of other owners are reported as missing, so that their ids are not confirmed.
+func (server *ImageServer) checkAccess(ctx context.Context, imageID string) error {
+	if _, admin := caller(ctx); admin {
+		return nil
+	}
+
+	imageFullInfo, err := server.imageStore.GetImageInfo(imageID)
+	if errors.Is(err, ErrImageNotFound) || (err == nil && !canAccess(ctx, imageFullInfo.GetOwner())) {
+		return status.Errorf(codes.NotFound, "cannot find image: %v", ErrImageNotFound)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot get image info: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// blobsFolderName holds the content of the images of a content addressed store, one blob per SHA-256.
+// Every image that refers to a blob holds a reference to it, in the trash too so that it can be restored.
+// The references are counted from the index when the store opens, they are not stored anywhere.
+const blobsFolderName = ".blobs"
+
+// blobPath shards the blobs by the first two pairs of hash characters, like the image files
+func (store *DiskImageStore) blobPath(hash string) string {
+	return filepath.Join(store.imageFolder, blobsFolderName, hash[0:2], hash[2:4], hash)
+}
+
+// storeBlob moves the written content into the blob of its hash, unless the blob is stored already.
+// It reports whether it was, the caller must hold the lock and count the reference once the image is indexed.
+func (store *DiskImageStore) storeBlob(tmpPath string, hash string) (bool, error) {
+	if store.blobRefs[hash] > 0 {
+		return true, nil
+	}
+
+	// a blob nothing refers to is left over from a save that did not get into the index, it is replaced
+	blobPath := store.blobPath(hash)
+	err := os.MkdirAll(filepath.Dir(blobPath), 0755)
+	if err != nil {
+		return false, fmt.Errorf("cannot create blob folder: %w", err)
+	}
+	err = os.Rename(tmpPath, blobPath)
+	if err != nil {
+		return false, fmt.Errorf("cannot move image file into place: %w", err)
+	}
+	return false, nil
+}
+
+// releaseBlob drops a reference to the blob and removes it with the last one,
+// the caller must hold the lock
+func (store *DiskImageStore) releaseBlob(hash string) error {
+	store.blobRefs[hash]--
+	if store.blobRefs[hash] > 0 {
+		return nil
+	}
+	delete(store.blobRefs, hash)
+
+	err := os.Remove(store.blobPath(hash))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove blob: %w", err)
+	}
+	slog.Info("removed blob", "blob", hash)
+	return nil
+}
+
+// removeContent removes the content of an image that is gone for good,
+// a blob only once no other image refers to it. The caller must hold the lock.
+func (store *DiskImageStore) removeContent(info *ImageInfo) error {
+	if info.Blob != "" {
+		return store.releaseBlob(info.Blob)
+	}
+
+	err := os.Remove(info.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot remove image file: %w", err)
+	}
+	return nil
+}
+
+// reconcileBlobs counts the references to every blob and removes the blobs no image refers to
+func (store *DiskImageStore) reconcileBlobs() error {
+	for _, info := range store.images {
+		if info.Blob != "" {
+			store.blobRefs[info.Blob]++
+		}
+	}
+
+	blobsFolder := filepath.Join(store.imageFolder, blobsFolderName)
+	blobPaths, err := filepath.Glob(filepath.Join(blobsFolder, "*", "*", "*"))
+	if err != nil {
+		return fmt.Errorf("cannot read blobs dir: %w", err)
+	}
+	for _, blobPath := range blobPaths {
+		hash := filepath.Base(blobPath)
+		if store.blobRefs[hash] > 0 && blobPath == store.blobPath(hash) {
+			continue
+		}
+
+		slog.Warn("removing unreferenced blob", "path", blobPath)
+		err := os.RemoveAll(blobPath)
+		if err != nil {
+			return fmt.Errorf("cannot remove blob: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// migrateToBlobs moves the images stored under their id into blobs, so that uploads of the same content share them.
+// The blob is linked to the file before the index refers to it, an interrupted migration leaves the image where it was.
+func (store *DiskImageStore) migrateToBlobs() error {
+	migrated := 0
+	for imageID, info := range store.images {
+		if info.Blob != "" {
+			continue
+		}
+
+		// the checksum of an image that was changed outside the store cannot be trusted
+		hash, err := fileChecksum(info.Path)
+		if err != nil {
+			return err
+		}
+
+		if store.blobRefs[hash] == 0 {
+			blobPath := store.blobPath(hash)
+			err = os.MkdirAll(filepath.Dir(blobPath), 0755)
+			if err != nil {
+				return fmt.Errorf("cannot create blob folder: %w", err)
+			}
+			err = os.Link(info.Path, blobPath)
+			if err != nil {
+				return fmt.Errorf("cannot move image file into a blob: %w", err)
+			}
+		}
+
+		migratedInfo := *info
+		migratedInfo.Checksum = hash
+		migratedInfo.Blob = hash
+		migratedInfo.Path = store.blobPath(hash)
+		err = store.index.put(&migratedInfo)
+		if err != nil {
+			return err
+		}
+		store.putImage(&migratedInfo)
+		store.blobRefs[hash]++
+
+		err = os.Remove(info.Path)
+		if err != nil {
+			slog.Warn("cannot remove migrated image file", "image_id", imageID, "error", err)
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		slog.Info("moved images into blobs", "images", migrated, "blobs", len(store.blobRefs))
+	}
+	return nil
+}
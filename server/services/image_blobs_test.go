@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+func openContentStore(t *testing.T, folder string) *DiskImageStore {
+	t.Helper()
+	store, err := NewContentImageStore(folder, time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewContentImageStore: %v", err)
+	}
+	return store
+}
+
+func saveImage(t *testing.T, store *DiskImageStore, meta ImageMeta, data string) *ImageInfo {
+	t.Helper()
+	info, err := store.Save(context.Background(), meta, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Save %s: %v", meta.Name, err)
+	}
+	return info
+}
+
+func readImage(t *testing.T, store *DiskImageStore, imageID string) string {
+	t.Helper()
+	_, image, err := store.Open(imageID)
+	if err != nil {
+		t.Fatalf("Open %s: %v", imageID, err)
+	}
+	defer image.Close()
+	data, err := io.ReadAll(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func blobExists(t *testing.T, store *DiskImageStore, hash string) bool {
+	t.Helper()
+	_, err := os.Stat(store.blobPath(hash))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestContentImageStoreReclaimsBlobWithLastReference(t *testing.T) {
+	folder := t.TempDir()
+	store := openContentStore(t, folder)
+
+	first := saveImage(t, store, ImageMeta{Name: "a.png"}, "same")
+	second := saveImage(t, store, ImageMeta{Name: "b.png"}, "same")
+	if first.Deduplicated || !second.Deduplicated {
+		t.Errorf("deduplicated: got %v and %v, want only the second", first.Deduplicated, second.Deduplicated)
+	}
+	if first.Blob == "" || first.Blob != second.Blob {
+		t.Fatalf("images are stored in blobs %q and %q, want one shared blob", first.Blob, second.Blob)
+	}
+	hash := first.Blob
+	if refs := store.blobRefs[hash]; refs != 2 {
+		t.Errorf("blob has %d references, want 2", refs)
+	}
+
+	// the image in the trash keeps its reference until it is purged
+	if err := store.Delete(first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if refs := store.blobRefs[hash]; refs != 2 {
+		t.Errorf("blob has %d references with one image in the trash, want 2", refs)
+	}
+	if _, err := store.PurgeTrash(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if !blobExists(t, store, hash) {
+		t.Fatal("blob is removed while another image refers to it")
+	}
+	if data := readImage(t, store, second.ID); data != "same" {
+		t.Errorf("remaining image reads %q", data)
+	}
+
+	if err := store.Delete(second.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// the references are counted from the index again, the image in the trash still holds one
+	store = openContentStore(t, folder)
+	defer store.Close()
+	if refs := store.blobRefs[hash]; refs != 1 {
+		t.Errorf("reopened store counts %d references, want 1", refs)
+	}
+	if _, err := store.PurgeTrash(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	if blobExists(t, store, hash) {
+		t.Error("blob is left behind after its last image is purged")
+	}
+	if len(store.blobRefs) != 0 {
+		t.Errorf("references are left: %v", store.blobRefs)
+	}
+}
+
+func TestContentImageStoreOverwriteReleasesBlob(t *testing.T) {
+	store := openContentStore(t, t.TempDir())
+	defer store.Close()
+
+	first := saveImage(t, store, ImageMeta{Name: "a.png"}, "old")
+	second := saveImage(t, store, ImageMeta{Name: "b.png"}, "old")
+	oldHash := first.Blob
+
+	overwrite := protos.ConflictPolicy_OVERWRITE
+	newInfo := saveImage(t, store, ImageMeta{Name: "a.png", ConflictPolicy: overwrite}, "new")
+	if newInfo.ID != first.ID || newInfo.Blob == oldHash {
+		t.Fatalf("OVERWRITE stored image %s in blob %s, want image %s in a new blob", newInfo.ID, newInfo.Blob, first.ID)
+	}
+	if !blobExists(t, store, oldHash) || store.blobRefs[oldHash] != 1 {
+		t.Errorf("old blob with %d references, want it kept for the other image", store.blobRefs[oldHash])
+	}
+	if data := readImage(t, store, second.ID); data != "old" {
+		t.Errorf("other image reads %q, want old", data)
+	}
+
+	saveImage(t, store, ImageMeta{Name: "b.png", ConflictPolicy: overwrite}, "new")
+	if blobExists(t, store, oldHash) {
+		t.Error("old blob is left behind after both images are overwritten")
+	}
+	if refs := store.blobRefs[newInfo.Blob]; refs != 2 {
+		t.Errorf("new blob has %d references, want 2", refs)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		if data := readImage(t, store, id); data != "new" {
+			t.Errorf("image %s reads %q, want new", id, data)
+		}
+	}
+}
+
+func TestContentImageStoreMigratesPlainImages(t *testing.T) {
+	folder := t.TempDir()
+	plain, err := NewDiskImageStore(folder, time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	images := map[string]string{}
+	for name, data := range map[string]string{"a.png": "same", "b.png": "same", "c.png": "other"} {
+		images[saveImage(t, plain, ImageMeta{Name: name}, data).ID] = data
+	}
+	var plainPaths []string
+	for _, info := range plain.images {
+		plainPaths = append(plainPaths, info.Path)
+	}
+	if err := plain.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// a blob that no image refers to is left over from an interrupted save
+	stray := filepath.Join(folder, blobsFolderName, "ab", "cd", "abcd")
+	if err := os.MkdirAll(filepath.Dir(stray), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stray, []byte("stray"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := openContentStore(t, folder)
+	defer store.Close()
+
+	wantRefs := map[string]int{"same": 2, "other": 1}
+	for id, data := range images {
+		info := store.images[id]
+		if info.Blob == "" {
+			t.Errorf("image %s is not moved into a blob", id)
+			continue
+		}
+		if got := store.blobRefs[info.Blob]; got != wantRefs[data] {
+			t.Errorf("blob of image %s has %d references, want %d", id, got, wantRefs[data])
+		}
+		if got := readImage(t, store, id); got != data {
+			t.Errorf("image %s reads %q, want %q", id, got, data)
+		}
+	}
+	if len(store.blobRefs) != 2 {
+		t.Errorf("got %d blobs, want 2 for the two contents", len(store.blobRefs))
+	}
+	for _, path := range plainPaths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("image file %s is left behind after the migration", path)
+		}
+	}
+	if _, err := os.Stat(stray); !os.IsNotExist(err) {
+		t.Error("unreferenced blob is not removed")
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const indexFileName = ".index.jsonl"
+
+const (
+	// compactionRatio is how many records the journal may hold per image before it is compacted
+	compactionRatio = 4
+	// minCompactionRecords keeps small journals from being compacted on every change
+	minCompactionRecords = 1000
+)
+
+const (
+	indexOpPut    = "put"
+	indexOpDelete = "delete"
+)
+
+// indexRecord is a single line of the index journal
+type indexRecord struct {
+	Op    string     `json:"op"`
+	Image *ImageInfo `json:"image,omitempty"`
+	ID    string     `json:"id,omitempty"`
+}
+
+// imageIndex is an append-only journal of image metadata kept inside the image folder
+type imageIndex struct {
+	path string
+	file *os.File
+	// records is the number of lines in the journal
+	records int
+}
+
+// openImageIndex replays the journal in the given folder and opens it for appending
+func openImageIndex(imageFolder string) (*imageIndex, map[string]*ImageInfo, error) {
+	index := &imageIndex{
+		path: filepath.Join(imageFolder, indexFileName),
+	}
+
+	images, err := index.replay()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index.file, err = os.OpenFile(index.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open index file: %w", err)
+	}
+
+	return index, images, nil
+}
+
+func (index *imageIndex) replay() (map[string]*ImageInfo, error) {
+	images := make(map[string]*ImageInfo)
+
+	file, err := os.Open(index.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return images, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot open index file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		index.records++
+		var record indexRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// a crash in the middle of an append leaves a torn last line
+			slog.Warn("skipping corrupted index record", "line", line, "error", err)
+			continue
+		}
+
+		switch record.Op {
+		case indexOpPut:
+			if record.Image != nil && record.Image.ID != "" {
+				images[record.Image.ID] = record.Image
+			}
+		case indexOpDelete:
+			delete(images, record.ID)
+		default:
+			slog.Warn("skipping unknown index record", "line", line, "op", record.Op)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read index file: %w", err)
+	}
+
+	return images, nil
+}
+
+func (index *imageIndex) put(info *ImageInfo) error {
+	return index.append(&indexRecord{Op: indexOpPut, Image: info})
+}
+
+func (index *imageIndex) remove(imageID string) error {
+	return index.append(&indexRecord{Op: indexOpDelete, ID: imageID})
+}
+
+func (index *imageIndex) append(record *indexRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("cannot encode index record: %w", err)
+	}
+
+	_, err = index.file.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("cannot write index record: %w", err)
+	}
+	index.records++
+
+	return index.file.Sync()
+}
+
+// compact replaces the journal with a single put record for every image
+func (index *imageIndex) compact(images map[string]*ImageInfo) error {
+	tmpPath := index.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot create index file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, info := range images {
+		if err := encoder.Encode(&indexRecord{Op: indexOpPut, Image: info}); err != nil {
+			file.Close()
+			return fmt.Errorf("cannot write index record: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot write index file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot sync index file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("cannot close index file: %w", err)
+	}
+
+	index.file.Close()
+	renameErr := os.Rename(tmpPath, index.path)
+
+	// reopen even if the rename failed so that appends keep going to the old journal
+	index.file, err = os.OpenFile(index.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open index file: %w", err)
+	}
+	if renameErr != nil {
+		return fmt.Errorf("cannot replace index file: %w", renameErr)
+	}
+	index.records = len(images)
+	return nil
+}
+
+// needsCompaction reports whether the journal has grown well past the images it describes
+func (index *imageIndex) needsCompaction(images int) bool {
+	return index.records > minCompactionRecords && index.records > compactionRatio*images
+}
+
+// check returns an error if the journal is not open for appending
+func (index *imageIndex) check() error {
+	if _, err := index.file.Stat(); err != nil {
+		return fmt.Errorf("index is not open: %w", err)
+	}
+	return nil
+}
+
+func (index *imageIndex) close() error {
+	return index.file.Close()
+}
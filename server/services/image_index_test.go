@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeIndexFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	folder := t.TempDir()
+	err := os.WriteFile(filepath.Join(folder, indexFileName), []byte(strings.Join(lines, "\n")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return folder
+}
+
+func TestImageIndexReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name:  "empty",
+			lines: nil,
+			want:  nil,
+		},
+		{
+			name: "put and delete",
+			lines: []string{
+				`{"op":"put","image":{"id":"a","name":"a.png"}}`,
+				`{"op":"put","image":{"id":"b","name":"b.png"}}`,
+				`{"op":"delete","id":"a"}`,
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "corrupt line in the middle",
+			lines: []string{
+				`{"op":"put","image":{"id":"a","name":"a.png"}}`,
+				`not json at all`,
+				`{"op":"put","image":{"id":"b","name":"b.png"}}`,
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "truncated last line",
+			lines: []string{
+				`{"op":"put","image":{"id":"a","name":"a.png"}}`,
+				`{"op":"put","image":{"id":"b","na`,
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "unknown op and put without id",
+			lines: []string{
+				`{"op":"put","image":{"id":"a","name":"a.png"}}`,
+				`{"op":"rename","id":"a"}`,
+				`{"op":"put","image":{"name":"c.png"}}`,
+			},
+			want: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := writeIndexFile(t, tt.lines...)
+			index, images, err := openImageIndex(folder)
+			if err != nil {
+				t.Fatalf("openImageIndex: %v", err)
+			}
+			defer index.close()
+
+			if len(images) != len(tt.want) {
+				t.Fatalf("got %d images, want %d", len(images), len(tt.want))
+			}
+			for _, id := range tt.want {
+				if _, ok := images[id]; !ok {
+					t.Errorf("image %s is missing", id)
+				}
+			}
+			if index.records != len(tt.lines) {
+				t.Errorf("got %d records, want %d", index.records, len(tt.lines))
+			}
+		})
+	}
+}
+
+func TestImageIndexReplayKeepsLastPut(t *testing.T) {
+	folder := writeIndexFile(t,
+		`{"op":"put","image":{"id":"a","name":"a.png"}}`,
+		`{"op":"put","image":{"id":"a","name":"renamed.png"}}`,
+	)
+	index, images, err := openImageIndex(folder)
+	if err != nil {
+		t.Fatalf("openImageIndex: %v", err)
+	}
+	defer index.close()
+
+	if got := images["a"].Name; got != "renamed.png" {
+		t.Errorf("got name %q, want renamed.png", got)
+	}
+}
+
+func TestImageIndexAppendAfterTruncatedLine(t *testing.T) {
+	folder := writeIndexFile(t, `{"op":"put","image":{"id":"a","name":"a.png"}}`, `{"op":"put","ima`)
+	index, _, err := openImageIndex(folder)
+	if err != nil {
+		t.Fatalf("openImageIndex: %v", err)
+	}
+	err = index.compact(map[string]*ImageInfo{"a": {ID: "a", Name: "a.png"}})
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	err = index.put(&ImageInfo{ID: "b", Name: "b.png"})
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	index.close()
+
+	index, images, err := openImageIndex(folder)
+	if err != nil {
+		t.Fatalf("openImageIndex: %v", err)
+	}
+	defer index.close()
+	if len(images) != 2 {
+		t.Errorf("got %d images after reopening, want 2", len(images))
+	}
+}
+
+func TestDiskImageStoreCompactsIndexWhileRunning(t *testing.T) {
+	folder := t.TempDir()
+	store, err := NewDiskImageStore(folder, time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	info, err := store.Save(context.Background(), ImageMeta{Name: "a.png"}, strings.NewReader("data"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	for i := 0; i < 3*minCompactionRecords; i++ {
+		_, err := store.Rename(info.ID, fmt.Sprintf("a-%d.png", i))
+		if err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+	}
+
+	if store.index.records > minCompactionRecords+1 {
+		t.Errorf("journal has %d records for one image, want it compacted", store.index.records)
+	}
+	data, err := os.ReadFile(filepath.Join(folder, indexFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != store.index.records {
+		t.Errorf("journal has %d lines, index counts %d records", lines, store.index.records)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+// maxImageNameLen is the usual file name limit of file systems, in bytes
+const maxImageNameLen = 255
+
+// ErrInvalidImageName is returned for names that are left empty after sanitising
+var ErrInvalidImageName = errors.New("invalid image name")
+
+// ConflictPolicy decides what happens when a saved image takes the name of an existing one
+type ConflictPolicy = protos.ConflictPolicy
+
+// sanitizeImageName reduces a client supplied name to a plain file name.
+// Directories are dropped, and so are control characters and leading dots.
+func sanitizeImageName(imageName string) (string, error) {
+	imageName = path.Base(strings.ReplaceAll(imageName, "\\", "/"))
+	imageName = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == utf8.RuneError || unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, imageName)
+	imageName = strings.TrimLeft(strings.TrimSpace(imageName), ".")
+
+	if len(imageName) > maxImageNameLen {
+		ext := path.Ext(imageName)
+		if len(ext) > maxImageNameLen/2 {
+			ext = ""
+		}
+		base := strings.ToValidUTF8(imageName[:maxImageNameLen-len(ext)], "")
+		imageName = base + ext
+	}
+
+	if imageName == "" {
+		return "", ErrInvalidImageName
+	}
+	return imageName, nil
+}
+
+// Rename gives the image a new name, the file stays where it is since it is stored under the id
+func (store *DiskImageStore) Rename(imageID string, imageName string) (*ImageInfo, error) {
+	imageName, err := sanitizeImageName(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	info, ok := store.images[imageID]
+	if !ok || info.DeletedAt != nil {
+		return nil, ErrImageNotFound
+	}
+	if info.Name == imageName {
+		return info, nil
+	}
+	if store.findByName(info.Owner, imageName) != nil {
+		return nil, fmt.Errorf("cannot rename image to %s: %w", imageName, ErrImageExists)
+	}
+
+	renamed := *info
+	renamed.Name = imageName
+	renamed.UpdatedAt = time.Now().UTC()
+
+	err = store.index.put(&renamed)
+	if err != nil {
+		return nil, err
+	}
+	store.putImage(&renamed)
+	store.compactIndex()
+
+	return &renamed, nil
+}
+
+// nameKey is the key of the names index, every owner has names of its own
+type nameKey struct {
+	owner string
+	name  string
+}
+
+// indexNames builds the names index from the images, the caller must hold the lock
+func (store *DiskImageStore) indexNames() {
+	store.names = make(map[nameKey]string, len(store.images))
+	for _, info := range store.images {
+		if info.DeletedAt == nil {
+			store.names[nameKey{info.Owner, info.Name}] = info.ID
+		}
+	}
+}
+
+// putImage adds the image or replaces its entry, and keeps the names index and the views up to date.
+// The caller must hold the lock.
+func (store *DiskImageStore) putImage(info *ImageInfo) {
+	if old, ok := store.images[info.ID]; ok {
+		store.unindexName(old)
+	}
+	store.images[info.ID] = info
+	if info.DeletedAt == nil {
+		store.names[nameKey{info.Owner, info.Name}] = info.ID
+	}
+	store.invalidateViews()
+}
+
+// removeImage drops the image from the images, the names index and the views, the caller must hold the lock
+func (store *DiskImageStore) removeImage(imageID string) {
+	if old, ok := store.images[imageID]; ok {
+		store.unindexName(old)
+	}
+	delete(store.images, imageID)
+	store.invalidateViews()
+}
+
+func (store *DiskImageStore) unindexName(info *ImageInfo) {
+	key := nameKey{info.Owner, info.Name}
+	if store.names[key] == info.ID {
+		delete(store.names, key)
+	}
+}
+
+// findByName returns the image of the owner that is not deleted and has the given name,
+// every owner has names of its own. The caller must hold the lock.
+func (store *DiskImageStore) findByName(owner string, imageName string) *ImageInfo {
+	imageID, ok := store.names[nameKey{owner, imageName}]
+	if !ok {
+		return nil
+	}
+	return store.images[imageID]
+}
+
+// uniqueName appends a counter to the name until no other image of the owner takes it,
+// the caller must hold the lock
+func (store *DiskImageStore) uniqueName(owner string, imageName string) string {
+	ext := path.Ext(imageName)
+	base := strings.TrimSuffix(imageName, ext)
+	for i := 1; ; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		if len(base)+len(suffix)+len(ext) > maxImageNameLen {
+			base = strings.ToValidUTF8(base[:maxImageNameLen-len(suffix)-len(ext)], "")
+		}
+		candidate := base + suffix + ext
+		if store.findByName(owner, candidate) == nil {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+func TestDiskImageStoreNamesPerOwner(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	alice, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "alice"}, strings.NewReader("alice"))
+	if err != nil {
+		t.Fatalf("Save alice: %v", err)
+	}
+	bob, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "bob"}, strings.NewReader("bob"))
+	if err != nil {
+		t.Fatalf("Save of the same name by another owner: %v", err)
+	}
+
+	overwritten, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "bob", ConflictPolicy: protos.ConflictPolicy_OVERWRITE}, strings.NewReader("bob 2"))
+	if err != nil {
+		t.Fatalf("Save with OVERWRITE: %v", err)
+	}
+	if overwritten.ID != bob.ID {
+		t.Errorf("OVERWRITE replaced image %s, want %s of the same owner", overwritten.ID, bob.ID)
+	}
+
+	renamed, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "alice", ConflictPolicy: protos.ConflictPolicy_AUTO_RENAME}, strings.NewReader("alice 2"))
+	if err != nil {
+		t.Fatalf("Save with AUTO_RENAME: %v", err)
+	}
+	if renamed.Name != "a (1).png" {
+		t.Errorf("AUTO_RENAME chose %q, want %q", renamed.Name, "a (1).png")
+	}
+
+	_, err = store.Rename(renamed.ID, "a.png")
+	if !errors.Is(err, ErrImageExists) {
+		t.Errorf("Rename onto a name of the same owner: got %v, want ErrImageExists", err)
+	}
+	other, err := store.Save(ctx, ImageMeta{Name: "b.png", Owner: "bob"}, strings.NewReader("b"))
+	if err != nil {
+		t.Fatalf("Save b.png: %v", err)
+	}
+	_, err = store.Rename(other.ID, "a (1).png")
+	if err != nil {
+		t.Errorf("Rename onto a name of another owner: %v", err)
+	}
+
+	for owner, want := range map[string]string{"alice": "alice", "bob": "bob 2"} {
+		info, image, err := store.OpenByName(owner, "a.png")
+		if err != nil {
+			t.Fatalf("OpenByName %s: %v", owner, err)
+		}
+		data, err := io.ReadAll(image)
+		image.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Owner != owner || string(data) != want {
+			t.Errorf("OpenByName %s: got %q of %s, want %q", owner, data, info.Owner, want)
+		}
+	}
+	if alice.ID == bob.ID {
+		t.Errorf("both owners got image %s", alice.ID)
+	}
+	_, _, err = store.OpenByName("carol", "a.png")
+	if !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("OpenByName of an owner without the name: got %v, want ErrImageNotFound", err)
+	}
+}
+
+func TestSanitizeImageName(t *testing.T) {
+	longName := strings.Repeat("é", 200) + ".png"
+
+	tests := []struct {
+		name      string
+		imageName string
+		want      string
+		wantErr   bool
+	}{
+		{"plain", "a.png", "a.png", false},
+		{"parent", "..", "", true},
+		{"current", ".", "", true},
+		{"root", "/", "", true},
+		{"empty", "", "", true},
+		{"traversal", "../../etc/x", "x", false},
+		{"traversal inside", "a/../../b", "b", false},
+		{"absolute", "/etc/passwd", "passwd", false},
+		{"backslashes", `..\..\windows\x.png`, "x.png", false},
+		{"mixed separators", `a\b/../c.png`, "c.png", false},
+		{"control characters", "a\x00b\n\x7f.png", "ab.png", false},
+		{"only control characters", "\x00\t", "", true},
+		{"leading dots", "...hidden.png", "hidden.png", false},
+		{"only dots and spaces", " .. ", "", true},
+		{"reserved characters", `a<b>:c"d|e?f*.png`, "a_b__c_d_e_f_.png", false},
+		{"invalid UTF-8", "a\xffb.png", "ab.png", false},
+		{"too long", longName, strings.Repeat("é", 125) + ".png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sanitizeImageName(tt.imageName)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidImageName) {
+					t.Errorf("got %q, %v, want ErrInvalidImageName", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sanitizeImageName: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(got) > maxImageNameLen || !utf8.ValidString(got) {
+				t.Errorf("got %d bytes of valid UTF-8 %v, want at most %d", len(got), utf8.ValidString(got), maxImageNameLen)
+			}
+		})
+	}
+}
+
+func TestDiskImageStoreUniqueName(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	tests := []struct {
+		name      string
+		imageName string
+		want      string
+	}{
+		{"short", "a.png", "a (1).png"},
+		{"without extension", "a", "a (1)"},
+		{"longest name", strings.Repeat("a", 251) + ".png", strings.Repeat("a", 247) + " (1).png"},
+		// a multi-byte character that does not fit is dropped as a whole
+		{"longest UTF-8 name", strings.Repeat("é", 125) + ".png", strings.Repeat("é", 123) + " (1).png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := ImageMeta{Name: tt.imageName, Owner: "alice", ConflictPolicy: protos.ConflictPolicy_AUTO_RENAME}
+			first, err := store.Save(context.Background(), meta, strings.NewReader("first"))
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if first.Name != tt.imageName {
+				t.Fatalf("first image is named %q, want %q", first.Name, tt.imageName)
+			}
+			second, err := store.Save(context.Background(), meta, strings.NewReader("second"))
+			if err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if second.Name != tt.want {
+				t.Errorf("AUTO_RENAME chose %q, want %q", second.Name, tt.want)
+			}
+			if len(second.Name) > maxImageNameLen || !utf8.ValidString(second.Name) {
+				t.Errorf("AUTO_RENAME chose %d bytes of valid UTF-8 %v", len(second.Name), utf8.ValidString(second.Name))
+			}
+		})
+	}
+
+	// the counter goes up until a name is free
+	store.mutex.Lock()
+	name := store.uniqueName("alice", "a.png")
+	store.mutex.Unlock()
+	if name != "a (2).png" {
+		t.Errorf("uniqueName chose %q, want %q", name, "a (2).png")
+	}
+}
+
+func TestDiskImageStoreNamesFollowTrash(t *testing.T) {
+	folder := t.TempDir()
+	store, err := NewDiskImageStore(folder, time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+
+	ctx := context.Background()
+	deleted, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "alice"}, strings.NewReader("first"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Delete(deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// the name of an image in the trash is free
+	if _, err := store.Save(ctx, ImageMeta{Name: "a.png", Owner: "alice"}, strings.NewReader("second")); err != nil {
+		t.Fatalf("Save over the name of a deleted image: %v", err)
+	}
+	if err := store.Restore(deleted.ID); !errors.Is(err, ErrImageExists) {
+		t.Errorf("Restore onto a taken name: got %v, want ErrImageExists", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// the names are indexed again when the store opens
+	store, err = NewDiskImageStore(folder, time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+	info, image, err := store.OpenByName("alice", "a.png")
+	if err != nil {
+		t.Fatalf("OpenByName after reopening: %v", err)
+	}
+	image.Close()
+	if info.ID == deleted.ID {
+		t.Errorf("OpenByName returned the deleted image")
+	}
+	renamed, err := store.Rename(info.ID, "b.png")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := store.Restore(deleted.ID); err != nil {
+		t.Errorf("Restore after the name is freed by a rename: %v", err)
+	}
+	_, image, err = store.OpenByName("alice", renamed.Name)
+	if err != nil {
+		t.Fatalf("OpenByName of the new name: %v", err)
+	}
+	image.Close()
+}
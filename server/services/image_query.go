@@ -0,0 +1,293 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// ErrInvalidQuery is returned for queries with a malformed pattern or page token
+var ErrInvalidQuery = errors.New("invalid image query")
+
+// SortOrder is the order in which images are listed
+type SortOrder = protos.GetImageInfoListRequest_SortOrder
+
+// ImageQuery selects a page of images from the store
+type ImageQuery struct {
+	IncludeDeleted bool
+	// NamePattern is a name prefix, or a glob if it contains any of *?[
+	NamePattern   string
+	ImageType     string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	SortOrder     SortOrder
+	PageSize      int
+	PageToken     string
+	// Owner limits the list to the images of one owner unless AllOwners is set
+	Owner     string
+	AllOwners bool
+}
+
+// pageCursor is the position of the last listed image, encoded into the page token
+type pageCursor struct {
+	SortOrder SortOrder `json:"o"`
+	Name      string    `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	ID        string    `json:"i"`
+}
+
+// GetImagesInfoList lists a page of the images that match the query and returns the token of the next page.
+// The token is empty on the last page.
+func (store *DiskImageStore) GetImagesInfoList(query ImageQuery) ([]*protos.ImageFullInfo, string, error) {
+	matches, err := query.matcher()
+	if err != nil {
+		return nil, "", err
+	}
+
+	pageSize := query.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	view := store.sortedView(query.SortOrder)
+	less := imageLess(query.SortOrder)
+
+	start := 0
+	if query.PageToken != "" {
+		cursor, err := decodePageToken(query.PageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.SortOrder != query.SortOrder {
+			return nil, "", fmt.Errorf("%w: page token is for another sort order", ErrInvalidQuery)
+		}
+		after := &ImageInfo{ID: cursor.ID, Name: cursor.Name, CreatedAt: cursor.CreatedAt}
+		start = sort.Search(len(view), func(i int) bool {
+			return less(after, view[i])
+		})
+	}
+
+	imageInfoList := make([]*protos.ImageFullInfo, 0, pageSize)
+	nextPageToken := ""
+	for i := start; i < len(view); i++ {
+		info := view[i]
+		if !matches(info) {
+			continue
+		}
+		if len(imageInfoList) == pageSize {
+			nextPageToken = encodePageToken(query.SortOrder, view[i-1])
+			break
+		}
+
+		imageFullInfo, err := store.imageFullInfo(info)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		imageInfoList = append(imageInfoList, imageFullInfo)
+	}
+
+	return imageInfoList, nextPageToken, nil
+}
+
+// GetImageInfo returns the info of a single image, deleted images included
+func (store *DiskImageStore) GetImageInfo(imageID string) (*protos.ImageFullInfo, error) {
+	store.mutex.RLock()
+	info, ok := store.images[imageID]
+	store.mutex.RUnlock()
+	if !ok {
+		return nil, ErrImageNotFound
+	}
+
+	imageFullInfo, err := store.imageFullInfo(info)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrImageNotFound
+	}
+	return imageFullInfo, err
+}
+
+func (store *DiskImageStore) imageFullInfo(info *ImageInfo) (*protos.ImageFullInfo, error) {
+	fileInfo, err := os.Stat(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat image file: %w", err)
+	}
+
+	updatedAt := fileInfo.ModTime()
+	// a blob is shared with the images of the same content, its times belong to the first of them
+	if info.Blob != "" {
+		updatedAt = info.UpdatedAt
+	}
+
+	imageFullInfo := &protos.ImageFullInfo{
+		Id:        info.ID,
+		ImageName: info.Name,
+		// the recorded creation time is the one the images are sorted and filtered by
+		CreatedAt: info.CreatedAt.Local().Format(timeLayout),
+		UpdatedAt: updatedAt.Local().Format(timeLayout),
+		MimeType:  info.MimeType,
+		Width:     int32(info.Width),
+		Height:    int32(info.Height),
+		Checksum:  info.Checksum,
+		Size:      info.Size,
+		Owner:     info.Owner,
+	}
+	if info.DeletedAt != nil {
+		imageFullInfo.DeletedAt = info.DeletedAt.Local().Format(timeLayout)
+	}
+	return imageFullInfo, nil
+}
+
+// sortedView returns all the images in the given order.
+// Views are built on first use and dropped by every change to the images.
+func (store *DiskImageStore) sortedView(order SortOrder) []*ImageInfo {
+	store.mutex.RLock()
+	view, ok := store.views[order]
+	store.mutex.RUnlock()
+	if ok {
+		return view
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if view, ok := store.views[order]; ok {
+		return view
+	}
+
+	view = make([]*ImageInfo, 0, len(store.images))
+	for _, info := range store.images {
+		view = append(view, info)
+	}
+	less := imageLess(order)
+	sort.Slice(view, func(i, j int) bool {
+		return less(view[i], view[j])
+	})
+
+	if store.views == nil {
+		store.views = make(map[SortOrder][]*ImageInfo)
+	}
+	store.views[order] = view
+	return view
+}
+
+// invalidateViews drops the sorted views, the caller must hold the write lock
+func (store *DiskImageStore) invalidateViews() {
+	store.views = nil
+}
+
+// imageLess orders images by the sort key and then by id, so that the order is total
+func imageLess(order SortOrder) func(a, b *ImageInfo) bool {
+	switch order {
+	case protos.GetImageInfoListRequest_NAME_DESC:
+		return func(a, b *ImageInfo) bool {
+			if a.Name != b.Name {
+				return a.Name > b.Name
+			}
+			return a.ID > b.ID
+		}
+	case protos.GetImageInfoListRequest_CREATED_AT_ASC:
+		return func(a, b *ImageInfo) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		}
+	case protos.GetImageInfoListRequest_CREATED_AT_DESC:
+		return func(a, b *ImageInfo) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		}
+	default:
+		return func(a, b *ImageInfo) bool {
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ID < b.ID
+		}
+	}
+}
+
+func (query ImageQuery) matcher() (func(info *ImageInfo) bool, error) {
+	matchName := func(name string) bool {
+		return strings.HasPrefix(name, query.NamePattern)
+	}
+	if strings.ContainsAny(query.NamePattern, "*?[") {
+		if _, err := filepath.Match(query.NamePattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		matchName = func(name string) bool {
+			matched, _ := filepath.Match(query.NamePattern, name)
+			return matched
+		}
+	}
+
+	imageType := strings.TrimPrefix(query.ImageType, ".")
+
+	return func(info *ImageInfo) bool {
+		if info.DeletedAt != nil && !query.IncludeDeleted {
+			return false
+		}
+		if !query.AllOwners && info.Owner != query.Owner {
+			return false
+		}
+		if !matchName(info.Name) {
+			return false
+		}
+		if imageType != "" && !strings.EqualFold(strings.TrimPrefix(info.Type, "."), imageType) {
+			return false
+		}
+		if !query.CreatedAfter.IsZero() && info.CreatedAt.Before(query.CreatedAfter) {
+			return false
+		}
+		if !query.CreatedBefore.IsZero() && !info.CreatedAt.Before(query.CreatedBefore) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+func encodePageToken(order SortOrder, last *ImageInfo) string {
+	cursor := pageCursor{SortOrder: order, ID: last.ID}
+	switch order {
+	case protos.GetImageInfoListRequest_CREATED_AT_ASC, protos.GetImageInfoListRequest_CREATED_AT_DESC:
+		cursor.CreatedAt = last.CreatedAt
+	default:
+		cursor.Name = last.Name
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed page token", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+func TestImageInfoReportsRecordedCreationTime(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	info, err := store.Save(ctx, ImageMeta{Name: "a.png"}, strings.NewReader("first"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// the file of an overwritten image is newer than the image
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.mutex.Lock()
+	store.images[info.ID].CreatedAt = createdAt
+	store.mutex.Unlock()
+	_, err = store.Save(ctx, ImageMeta{Name: "a.png", ConflictPolicy: protos.ConflictPolicy_OVERWRITE}, strings.NewReader("second"))
+	if err != nil {
+		t.Fatalf("Save with OVERWRITE: %v", err)
+	}
+
+	want := createdAt.Local().Format(timeLayout)
+	fullInfo, err := store.GetImageInfo(info.ID)
+	if err != nil {
+		t.Fatalf("GetImageInfo: %v", err)
+	}
+	if fullInfo.CreatedAt != want {
+		t.Errorf("GetImageInfo reports created at %s, want %s", fullInfo.CreatedAt, want)
+	}
+
+	list, _, err := store.GetImagesInfoList(ImageQuery{CreatedBefore: createdAt.Add(time.Second)})
+	if err != nil {
+		t.Fatalf("GetImagesInfoList: %v", err)
+	}
+	if len(list) != 1 || list[0].CreatedAt != want {
+		t.Errorf("list created before %s: got %v, want the image created at %s", createdAt.Add(time.Second), list, want)
+	}
+}
+
+// listAll follows the page tokens of the query to the end and returns the ids of all the pages
+func listAll(t *testing.T, store *DiskImageStore, query ImageQuery) []string {
+	t.Helper()
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("page tokens do not come to an end")
+		}
+		list, nextPageToken, err := store.GetImagesInfoList(query)
+		if err != nil {
+			t.Fatalf("GetImagesInfoList: %v", err)
+		}
+		if len(list) > query.PageSize {
+			t.Fatalf("got a page of %d images, want at most %d", len(list), query.PageSize)
+		}
+		for _, info := range list {
+			ids = append(ids, info.Id)
+		}
+		if nextPageToken == "" {
+			return ids
+		}
+		query.PageToken = nextPageToken
+	}
+}
+
+// newPagingStore returns a store with images named img-00 to img-09 and other-00 to other-04,
+// created a minute apart in the reverse order of their names, and two of them at the same time
+func newPagingStore(t *testing.T) (*DiskImageStore, []*ImageInfo) {
+	t.Helper()
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	var names []string
+	for i := 0; i < 10; i++ {
+		names = append(names, fmt.Sprintf("img-%02d.png", i))
+	}
+	for i := 0; i < 5; i++ {
+		names = append(names, fmt.Sprintf("other-%02d.jpg", i))
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var images []*ImageInfo
+	for i, name := range names {
+		info, err := store.Save(context.Background(), ImageMeta{Name: name, Type: filepath.Ext(name)}, strings.NewReader(name))
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		created := *info
+		created.CreatedAt = start.Add(time.Duration(len(names)-i) * time.Minute)
+		if name == "img-04.png" {
+			created.CreatedAt = start.Add(time.Duration(len(names)-3) * time.Minute)
+		}
+		store.mutex.Lock()
+		store.putImage(&created)
+		store.mutex.Unlock()
+		images = append(images, &created)
+	}
+	return store, images
+}
+
+func TestGetImagesInfoListPages(t *testing.T) {
+	store, images := newPagingStore(t)
+
+	byName := func(a, b *ImageInfo) bool { return a.Name < b.Name }
+	byCreated := func(a, b *ImageInfo) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	}
+	expect := func(matches func(*ImageInfo) bool, less func(a, b *ImageInfo) bool, reverse bool) []string {
+		var selected []*ImageInfo
+		for _, info := range images {
+			if matches(info) {
+				selected = append(selected, info)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			if reverse {
+				return less(selected[j], selected[i])
+			}
+			return less(selected[i], selected[j])
+		})
+		ids := make([]string, len(selected))
+		for i, info := range selected {
+			ids[i] = info.ID
+		}
+		return ids
+	}
+	all := func(*ImageInfo) bool { return true }
+	prefix := func(info *ImageInfo) bool { return strings.HasPrefix(info.Name, "img-") }
+	odd := func(info *ImageInfo) bool {
+		matched, _ := filepath.Match("img-0[13579].png", info.Name)
+		return matched
+	}
+	jpg := func(info *ImageInfo) bool { return filepath.Ext(info.Name) == ".jpg" }
+	middle := func(info *ImageInfo) bool {
+		return !info.CreatedAt.Before(images[10].CreatedAt) && info.CreatedAt.Before(images[4].CreatedAt)
+	}
+
+	tests := []struct {
+		name  string
+		query ImageQuery
+		want  []string
+	}{
+		{"all by name", ImageQuery{}, expect(all, byName, false)},
+		{"all by name descending", ImageQuery{SortOrder: protos.GetImageInfoListRequest_NAME_DESC}, expect(all, byName, true)},
+		{"all by creation", ImageQuery{SortOrder: protos.GetImageInfoListRequest_CREATED_AT_ASC}, expect(all, byCreated, false)},
+		{"all by creation descending", ImageQuery{SortOrder: protos.GetImageInfoListRequest_CREATED_AT_DESC}, expect(all, byCreated, true)},
+		{"prefix", ImageQuery{NamePattern: "img-"}, expect(prefix, byName, false)},
+		{"glob by creation", ImageQuery{NamePattern: "img-0[13579].png", SortOrder: protos.GetImageInfoListRequest_CREATED_AT_ASC}, expect(odd, byCreated, false)},
+		{"type", ImageQuery{ImageType: "jpg", SortOrder: protos.GetImageInfoListRequest_NAME_DESC}, expect(jpg, byName, true)},
+		{
+			"time range",
+			ImageQuery{CreatedAfter: images[10].CreatedAt, CreatedBefore: images[4].CreatedAt, SortOrder: protos.GetImageInfoListRequest_CREATED_AT_DESC},
+			expect(middle, byCreated, true),
+		},
+	}
+	for _, tt := range tests {
+		for _, pageSize := range []int{1, 3, 4, 100} {
+			t.Run(fmt.Sprintf("%s in pages of %d", tt.name, pageSize), func(t *testing.T) {
+				query := tt.query
+				query.PageSize = pageSize
+				if got := listAll(t, store, query); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestGetImagesInfoListChangesBetweenPages(t *testing.T) {
+	store, images := newPagingStore(t)
+	query := ImageQuery{NamePattern: "img-", PageSize: 3}
+
+	first, token, err := store.GetImagesInfoList(query)
+	if err != nil {
+		t.Fatalf("GetImagesInfoList: %v", err)
+	}
+	if len(first) != 3 || token == "" {
+		t.Fatalf("got a first page of %d images and token %q", len(first), token)
+	}
+
+	// the last listed image and one of a later page are deleted, an image is added before and one after the cursor
+	for _, info := range []*ImageInfo{images[2], images[5]} {
+		if err := store.Delete(info.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	for _, name := range []string{"img-00a.png", "img-05a.png"} {
+		if _, err := store.Save(context.Background(), ImageMeta{Name: name}, strings.NewReader(name)); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	var names []string
+	for _, info := range first {
+		names = append(names, info.ImageName)
+	}
+	query.PageToken = token
+	for _, id := range listAll(t, store, query) {
+		info, err := store.GetImageInfo(id)
+		if err != nil {
+			t.Fatalf("GetImageInfo: %v", err)
+		}
+		names = append(names, info.ImageName)
+	}
+	want := []string{"img-00.png", "img-01.png", "img-02.png", "img-03.png", "img-04.png", "img-05a.png", "img-06.png", "img-07.png", "img-08.png", "img-09.png"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestGetImagesInfoListInvalidToken(t *testing.T) {
+	store, _ := newPagingStore(t)
+
+	_, token, err := store.GetImagesInfoList(ImageQuery{PageSize: 2, SortOrder: protos.GetImageInfoListRequest_CREATED_AT_ASC})
+	if err != nil || token == "" {
+		t.Fatalf("GetImagesInfoList: %v, token %q", err, token)
+	}
+
+	tests := []struct {
+		name  string
+		query ImageQuery
+	}{
+		{"token of another sort order", ImageQuery{PageToken: token, SortOrder: protos.GetImageInfoListRequest_NAME_ASC}},
+		{"malformed token", ImageQuery{PageToken: "not a token"}},
+		{"token that is not JSON", ImageQuery{PageToken: "bm90IGpzb24"}},
+		{"malformed glob", ImageQuery{NamePattern: "img-["}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := store.GetImagesInfoList(tt.query)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("got %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
@@ -1,125 +1,710 @@
 package services
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"io"
-	"log"
-
-	"golang.org/x/sync/semaphore"
+	"log/slog"
+	"sync"
+	"time"
 
 	pb "github.com/navruz-rakhimov/tages-project/protos"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-const (
-	maxImageSize = 1 << 20
-	port         = ":5001"
-)
+const downloadChunkSize = 1024
+
+// ImageServerLimits are the limits the image server enforces
+type ImageServerLimits struct {
+	MaxReadConns   int64
+	MaxStreamConns int64
+	MaxImageSize   int64
+	// MaxImagePixels is the largest width times height of an uploaded image
+	MaxImagePixels int64
+	// UploadByteBudget is how many bytes the uploads in flight may hold together, at least MaxImageSize
+	UploadByteBudget int64
+	// MaxQueueWait is how long a request waits for a free slot before it is turned away
+	MaxQueueWait time.Duration
+	// AllowedFormats are the formats uploaded images may have, out of ImageFormatNames
+	AllowedFormats []string
+}
 
 type ImageServer struct {
 	pb.UnimplementedImageServiceServer
-	imageStore       ImageStore
-	readImageInfoSem *semaphore.Weighted
-	uploadImageSem   *semaphore.Weighted
+	imageStore        ImageStore
+	uploadSessions    *UploadSessions
+	maxReadConns      int64
+	maxStreamConns    int64
+	maxImageSize      int64
+	maxImagePixels    int64
+	allowedFormats    map[string]bool
+	readAdmission     *admission
+	uploadAdmission   *admission
+	downloadAdmission *admission
+	// uploadBytes is the budget of the bytes all the uploads in flight may hold
+	uploadBytes   *admission
+	uploadMetrics uploadMetrics
+
+	// drainMutex orders new uploads against Drain, so that uploads is not added to while it is waited on
+	drainMutex sync.Mutex
+	draining   bool
+	// uploads counts the upload streams in flight and the variant generation they start
+	uploads sync.WaitGroup
+	// variantsCtx ends the variant generation that is still running when Wait runs out of time
+	variantsCtx    context.Context
+	cancelVariants context.CancelFunc
 }
 
-func NewImageServer(imageStore ImageStore, maxReadConns int64, maxUploadImageConns int64) *ImageServer {
+func NewImageServer(imageStore ImageStore, uploadSessions *UploadSessions, limits ImageServerLimits) *ImageServer {
+	allowedFormats := make(map[string]bool, len(limits.AllowedFormats))
+	for _, format := range limits.AllowedFormats {
+		allowedFormats[format] = true
+	}
+
+	variantsCtx, cancelVariants := context.WithCancel(context.Background())
 	return &ImageServer{
-		imageStore:       imageStore,
-		readImageInfoSem: semaphore.NewWeighted(maxReadConns),
-		uploadImageSem:   semaphore.NewWeighted(maxUploadImageConns),
+		imageStore:        imageStore,
+		uploadSessions:    uploadSessions,
+		maxReadConns:      limits.MaxReadConns,
+		maxStreamConns:    limits.MaxStreamConns,
+		maxImageSize:      limits.MaxImageSize,
+		maxImagePixels:    limits.MaxImagePixels,
+		allowedFormats:    allowedFormats,
+		readAdmission:     newAdmission("read", limits.MaxReadConns, limits.MaxQueueWait),
+		uploadAdmission:   newAdmission("upload", limits.MaxStreamConns, limits.MaxQueueWait),
+		downloadAdmission: newAdmission("download", limits.MaxStreamConns, limits.MaxQueueWait),
+		uploadBytes:       newWeightedAdmission("upload bytes", "bytes", limits.UploadByteBudget, limits.MaxQueueWait),
+		uploadMetrics:     newUploadMetrics(),
+		variantsCtx:       variantsCtx,
+		cancelVariants:    cancelVariants,
 	}
 }
 
-func (server *ImageServer) GetImageInfoList(ctx context.Context, _ *pb.Empty) (*pb.GetImageInfoListResponse, error) {
-	if err := server.readImageInfoSem.Acquire(context.Background(), 1); err != nil {
+func (server *ImageServer) GetImageInfoList(ctx context.Context, req *pb.GetImageInfoListRequest) (*pb.GetImageInfoListResponse, error) {
+	if err := server.readAdmission.acquire(ctx); err != nil {
 		return nil, err
 	}
-	defer func() {
-		server.readImageInfoSem.Release(1)
-	}()
+	defer server.readAdmission.release()
+
+	query := ImageQuery{
+		IncludeDeleted: req.GetIncludeDeleted(),
+		NamePattern:    req.GetNamePattern(),
+		ImageType:      req.GetImageType(),
+		SortOrder:      req.GetSortOrder(),
+		PageSize:       int(req.GetPageSize()),
+		PageToken:      req.GetPageToken(),
+	}
+
+	owner, admin := caller(ctx)
+	if req.GetAllOwners() && !admin {
+		return nil, status.Errorf(codes.PermissionDenied, "cannot list the images of all owners: caller is not an admin")
+	}
+	// without authentication everyone is an admin and there are no owners to tell apart
+	query.Owner = owner
+	query.AllOwners = req.GetAllOwners() || !isAuthenticated(ctx)
+	if req.GetCreatedAfter() != nil {
+		query.CreatedAfter = req.GetCreatedAfter().AsTime()
+	}
+	if req.GetCreatedBefore() != nil {
+		query.CreatedBefore = req.GetCreatedBefore().AsTime()
+	}
 
-	imageFullInfoList, err := server.imageStore.GetImagesInfoList()
+	imageFullInfoList, nextPageToken, err := server.imageStore.GetImagesInfoList(query)
+	if errors.Is(err, ErrInvalidQuery) {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot list images: %v", err)
+	}
 	if err != nil {
-		log.Fatal("Could get full image info list from image store: ", err)
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "cannot list images: %v", err)
 	}
 	return &pb.GetImageInfoListResponse{
-		ImageInfos: imageFullInfoList,
+		ImageInfos:    imageFullInfoList,
+		NextPageToken: nextPageToken,
 	}, nil
 }
 
 func (server *ImageServer) UploadImage(stream pb.ImageService_UploadImageServer) error {
-	if err := server.uploadImageSem.Acquire(context.Background(), 1); err != nil {
+	if err := server.beginUpload(); err != nil {
 		return err
 	}
-	defer func() {
-		server.uploadImageSem.Release(1)
-	}()
+	defer server.uploads.Done()
+
+	// waiting for a slot ends with the stream, so that a shutdown does not hang on queued uploads
+	if err := server.uploadAdmission.acquire(stream.Context()); err != nil {
+		return err
+	}
+	defer server.uploadAdmission.release()
 
 	req, err := stream.Recv()
 	if err != nil {
-		return logError(status.Errorf(codes.Unknown, "cannot receive image info"))
+		return receiveError("image info", err)
+	}
+	if chunk := req.GetChunk(); chunk != nil {
+		return server.continueUpload(stream, chunk)
 	}
+
 	imageType := req.GetInfo().GetImageType()
 	imageName := req.GetInfo().GetImageName()
-	log.Printf("receive an upload-image request for image with type %s", imageType)
-	imageData := bytes.Buffer{}
-	imageSize := 0
+	owner, _ := caller(stream.Context())
+	meta := ImageMeta{
+		Name:           imageName,
+		Type:           imageType,
+		ConflictPolicy: req.GetInfo().GetConflictPolicy(),
+		Owner:          owner,
+		Size:           int64(req.GetInfo().GetSize()),
+	}
+	chunkReader := newImageChunkReader(stream, &server.uploadMetrics)
+	defer chunkReader.endSpan(nil)
+	info, err := server.receiveImage(stream.Context(), meta, chunkReader)
+	if err != nil {
+		return err
+	}
+
+	res := &pb.UploadImageResponse{
+		Id:           info.ID,
+		ImageName:    info.Name,
+		Size:         uint32(info.Size),
+		Deduplicated: info.Deduplicated,
+	}
+	err = stream.SendAndClose(res)
+	if err != nil {
+		return status.Errorf(codes.Unknown, "cannot send response: %v", err)
+	}
+	return nil
+}
+
+// receiveImage validates the image read from imageData and saves it to the store, for uploads over gRPC and REST.
+// The declared size of the image, or the largest one if it has none, is taken from the byte budget until the image is saved.
+// Errors of imageData that are not status errors are reported as failed receives.
+func (server *ImageServer) receiveImage(ctx context.Context, meta ImageMeta, imageData io.Reader) (*ImageInfo, error) {
+	slog.DebugContext(ctx, "receive upload", "image_name", meta.Name, "image_type", meta.Type, "declared_size", meta.Size)
+
+	if meta.Size < 0 || meta.Size > server.maxImageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot accept image: declared size %d is not between 0 and %d", meta.Size, server.maxImageSize)
+	}
+	maxSize := server.maxImageSize
+	if meta.Size > 0 {
+		maxSize = meta.Size
+	}
+	reader := &budgetReader{
+		r:           imageData,
+		reservation: uploadReservation{budget: server.uploadBytes, maxSize: maxSize},
+	}
+	if err := reader.reservation.reserve(ctx); err != nil {
+		return nil, err
+	}
+	defer reader.reservation.release()
+
+	validData, err := server.validateImage(ctx, &meta, reader)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot accept image: %v", err)
+	}
+
+	info, err := server.imageStore.Save(ctx, meta, validData)
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	if err != nil {
+		return nil, saveImageError(err)
+	}
+	slog.InfoContext(ctx, "saved image", "image_id", info.ID, "image_name", info.Name, "size", info.Size, "deduplicated", info.Deduplicated)
+	server.imageSaved(info)
+	return info, nil
+}
+
+// imageSaved counts the uploaded image and starts generating its variants in the background
+func (server *ImageServer) imageSaved(info *ImageInfo) {
+	server.uploadMetrics.observeImage(info.Size)
+	server.uploads.Add(1)
+	go server.generateVariants(info.ID)
+}
+
+// validateImage sniffs the format and dimensions of the image into meta, see sniffImage
+func (server *ImageServer) validateImage(ctx context.Context, meta *ImageMeta, imageData io.Reader) (io.Reader, error) {
+	_, span := tracer.Start(ctx, "validate image")
+	imageData, err := sniffImage(meta, imageData, server.allowedFormats, server.maxImagePixels)
+	if err == nil {
+		span.SetAttributes(
+			attribute.String("image.mime_type", meta.MimeType),
+			attribute.Int("image.width", meta.Width),
+			attribute.Int("image.height", meta.Height),
+		)
+	}
+	endSpan(span, err)
+	return imageData, err
+}
+
+func (server *ImageServer) StartUpload(ctx context.Context, req *pb.StartUploadRequest) (*pb.StartUploadResponse, error) {
+	imageName := req.GetInfo().GetImageName()
+	imageSize := req.GetSize()
+	slog.DebugContext(ctx, "start upload", "image_name", imageName, "size", imageSize)
+
+	if server.isDraining() {
+		return nil, status.Error(codes.Unavailable, "cannot start upload: server is shutting down")
+	}
+	if imageSize > uint64(server.maxImageSize) {
+		return nil, status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", imageSize, server.maxImageSize)
+	}
+	// the name is checked again on save, but a bad one should not wait for the whole upload
+	if _, err := sanitizeImageName(imageName); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot start upload: %v", err)
+	}
+
+	owner, _ := caller(ctx)
+	meta := ImageMeta{
+		Name:           imageName,
+		Type:           req.GetInfo().GetImageType(),
+		ConflictPolicy: req.GetInfo().GetConflictPolicy(),
+		Owner:          owner,
+	}
+	session, err := server.uploadSessions.Start(meta, int64(imageSize))
+	if errors.Is(err, ErrTooManyUploads) {
+		return nil, status.Errorf(codes.ResourceExhausted, "cannot start upload: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot start upload: %v", err)
+	}
+
+	slog.InfoContext(ctx, "started upload", "upload_id", session.ID, "image_name", imageName)
+	return &pb.StartUploadResponse{
+		UploadId: session.ID,
+	}, nil
+}
+
+func (server *ImageServer) GetUploadStatus(ctx context.Context, req *pb.GetUploadStatusRequest) (*pb.GetUploadStatusResponse, error) {
+	owner, _ := caller(ctx)
+	uploadStatus, err := server.uploadSessions.Status(req.GetUploadId(), owner)
+	if errors.Is(err, ErrUploadNotFound) {
+		return nil, status.Errorf(codes.NotFound, "cannot find upload: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot get upload status: %v", err)
+	}
+
+	return &pb.GetUploadStatusResponse{
+		UploadId:      req.GetUploadId(),
+		CommittedSize: uint64(uploadStatus.Committed),
+		Size:          uint64(uploadStatus.Size),
+		ImageId:       uploadStatus.ImageID,
+	}, nil
+}
+
+// continueUpload writes the chunks of a resumable upload, starting with the first one already received.
+// Once all the declared bytes are there the image is saved to the store.
+func (server *ImageServer) continueUpload(stream pb.ImageService_UploadImageServer, chunk *pb.UploadChunk) error {
+	owner, _ := caller(stream.Context())
+	session, err := server.uploadSessions.Acquire(chunk.GetUploadId(), owner)
+	if errors.Is(err, ErrUploadNotFound) {
+		return status.Errorf(codes.NotFound, "cannot find upload: %v", err)
+	}
+	if errors.Is(err, ErrUploadBusy) {
+		return status.Errorf(codes.Aborted, "cannot continue upload: %v", err)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot continue upload: %v", err)
+	}
+	defer server.uploadSessions.Release(session)
+	slog.DebugContext(stream.Context(), "continue upload", "upload_id", session.ID, "image_name", session.ImageName, "offset", chunk.GetOffset())
+
+	// the rest of the declared size is taken from the budget before any of it is received
+	remaining := session.Size - session.Committed()
+	if err := server.uploadBytes.acquireWeight(stream.Context(), remaining); err != nil {
+		return err
+	}
+	defer server.uploadBytes.releaseWeight(remaining)
+
+	_, span := tracer.Start(stream.Context(), "receive chunks", trace.WithAttributes(
+		attribute.String("upload.id", session.ID),
+		attribute.Int64("upload.offset", int64(chunk.GetOffset())),
+	))
+	err = server.receiveChunks(stream, session, chunk)
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+
+	// the upload is finished one way or another, a failed save cannot be resumed
+	defer server.uploadSessions.Remove(session)
+
+	imageFile, err := server.uploadSessions.Open(session)
+	if err != nil {
+		return status.Errorf(codes.Internal, "cannot read upload: %v", err)
+	}
+	defer imageFile.Close()
+
+	meta := ImageMeta{
+		Name:           session.ImageName,
+		Type:           session.ImageType,
+		ConflictPolicy: session.ConflictPolicy,
+		Owner:          session.Owner,
+	}
+	imageData, err := server.validateImage(stream.Context(), &meta, imageFile)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "cannot accept image: %v", err)
+	}
+
+	info, err := server.imageStore.Save(stream.Context(), meta, imageData)
+	if err != nil {
+		return saveImageError(err)
+	}
+	// a client that lost the response finds the image id in the upload status
+	server.uploadSessions.Saved(session, info.ID)
+
+	res := &pb.UploadImageResponse{
+		Id:           info.ID,
+		ImageName:    info.Name,
+		Size:         uint32(info.Size),
+		Deduplicated: info.Deduplicated,
+	}
+	err = stream.SendAndClose(res)
+	if err != nil {
+		return status.Errorf(codes.Unknown, "cannot send response: %v", err)
+	}
+	slog.InfoContext(stream.Context(), "saved image", "image_id", info.ID, "image_name", info.Name, "size", info.Size, "deduplicated", info.Deduplicated, "upload_id", session.ID)
+	server.imageSaved(info)
+	return nil
+}
+
+// receiveChunks writes the chunks of the stream to the upload session until it has all the declared bytes
+func (server *ImageServer) receiveChunks(stream pb.ImageService_UploadImageServer, session *UploadSession, chunk *pb.UploadChunk) error {
 	for {
-		log.Print("waiting to receive more data")
+		if chunk.GetUploadId() != session.ID {
+			return status.Errorf(codes.InvalidArgument, "chunk belongs to another upload: %s", chunk.GetUploadId())
+		}
+
+		server.uploadMetrics.observeChunk(len(chunk.GetData()))
+		err := session.Write(int64(chunk.GetOffset()), chunk.GetData())
+		if errors.Is(err, ErrUploadOffset) {
+			return status.Errorf(codes.FailedPrecondition, "cannot write chunk: %v", err)
+		}
+		if errors.Is(err, ErrUploadOverflow) {
+			return status.Errorf(codes.InvalidArgument, "cannot write chunk: %v", err)
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "cannot write chunk: %v", err)
+		}
+		if session.Complete() {
+			return nil
+		}
+
 		req, err := stream.Recv()
 		if err == io.EOF {
-			log.Print("no more data")
+			return status.Errorf(codes.FailedPrecondition, "upload is incomplete: %d of %d bytes received", session.Committed(), session.Size)
+		}
+		if err != nil {
+			return receiveError("chunk data", err)
+		}
+		chunk = req.GetChunk()
+		if chunk == nil {
+			return status.Errorf(codes.InvalidArgument, "expected a chunk of upload %s", session.ID)
+		}
+	}
+}
+
+func (server *ImageServer) DownloadImage(req *pb.DownloadImageRequest, stream pb.ImageService_DownloadImageServer) error {
+	if err := server.downloadAdmission.acquire(stream.Context()); err != nil {
+		return err
+	}
+	defer server.downloadAdmission.release()
+
+	info, image, err := server.openImage(stream.Context(), req.GetId(), req.GetImageName())
+	if err != nil {
+		return err
+	}
+	defer image.Close()
+
+	return sendImage(stream, info, image)
+}
+
+// openImage opens the image with the id, or with the name if the id is empty, for a download.
+// Names are looked up among the images of the caller, images of other owners are reported as missing.
+func (server *ImageServer) openImage(ctx context.Context, id string, name string) (*ImageInfo, io.ReadSeekCloser, error) {
+	var (
+		info  *ImageInfo
+		image io.ReadSeekCloser
+		err   error
+	)
+	switch {
+	case id != "":
+		slog.DebugContext(ctx, "download image", "image_id", id)
+		info, image, err = server.imageStore.Open(id)
+	case name != "":
+		slog.DebugContext(ctx, "download image", "image_name", name)
+		owner, _ := caller(ctx)
+		info, image, err = server.imageStore.OpenByName(owner, name)
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "image id or name is required")
+	}
+	if errors.Is(err, ErrImageNotFound) {
+		return nil, nil, status.Errorf(codes.NotFound, "cannot find image: %v", err)
+	}
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "cannot open image from the store: %v", err)
+	}
+	if !canAccess(ctx, info.Owner) {
+		image.Close()
+		return nil, nil, status.Errorf(codes.NotFound, "cannot find image: %v", ErrImageNotFound)
+	}
+	return info, image, nil
+}
+
+func (server *ImageServer) GetImageVariant(req *pb.GetImageVariantRequest, stream pb.ImageService_GetImageVariantServer) error {
+	if err := server.downloadAdmission.acquire(stream.Context()); err != nil {
+		return err
+	}
+	defer server.downloadAdmission.release()
+
+	slog.DebugContext(stream.Context(), "download image variant", "image_id", req.GetId(), "variant", req.GetVariant())
+	if err := server.checkAccess(stream.Context(), req.GetId()); err != nil {
+		return err
+	}
+	info, image, err := server.imageStore.OpenVariant(req.GetId(), req.GetVariant())
+	switch {
+	case errors.Is(err, ErrImageNotFound), errors.Is(err, ErrVariantNotFound):
+		return status.Errorf(codes.NotFound, "cannot find image variant: %v", err)
+	case errors.Is(err, ErrUnsupportedImage), errors.Is(err, ErrImageTooLarge):
+		return status.Errorf(codes.FailedPrecondition, "cannot generate image variant: %v", err)
+	case err != nil:
+		return status.Errorf(codes.Internal, "cannot open image variant from the store: %v", err)
+	}
+	defer image.Close()
+
+	return sendImage(stream, info, image)
+}
+
+// imageSender is a server stream that sends image info followed by chunks
+type imageSender interface {
+	Send(*pb.DownloadImageResponse) error
+	Context() context.Context
+}
+
+func sendImage(stream imageSender, info *ImageInfo, image io.Reader) error {
+	res := &pb.DownloadImageResponse{
+		Data: &pb.DownloadImageResponse_Info{
+			Info: &pb.ImageInfo{
+				ImageType: info.Type,
+				ImageName: info.Name,
+			},
+		},
+	}
+	err := stream.Send(res)
+	if err != nil {
+		return status.Errorf(codes.Unknown, "cannot send image info: %v", err)
+	}
+
+	buffer := make([]byte, downloadChunkSize)
+	imageSize := 0
+	for {
+		n, err := image.Read(buffer)
+		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return logError(status.Errorf(codes.Unknown, "cannot receive chunk data: %v", err))
+			return status.Errorf(codes.Internal, "cannot read image data: %v", err)
 		}
-		chunk := req.GetChunkData()
-		size := len(chunk)
-		log.Printf("received a chunk with size: %d", size)
-		imageSize += size
-		if imageSize > maxImageSize {
-			return logError(status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", imageSize, maxImageSize))
+
+		res := &pb.DownloadImageResponse{
+			Data: &pb.DownloadImageResponse_ChunkData{
+				ChunkData: buffer[:n],
+			},
 		}
-		_, err = imageData.Write(chunk)
+		err = stream.Send(res)
 		if err != nil {
-			return logError(status.Errorf(codes.Internal, "cannot write chunk data: %v", err))
+			return status.Errorf(codes.Unknown, "cannot send chunk data: %v", err)
 		}
+		imageSize += n
 	}
-	imageID, err := server.imageStore.Save(imageName, imageType, imageData)
+	slog.DebugContext(stream.Context(), "sent image", "image_id", info.ID, "image_name", info.Name, "size", imageSize)
+	return nil
+}
+
+// generateVariants runs after an upload, variants that fail here are generated on first request
+func (server *ImageServer) generateVariants(imageID string) {
+	defer server.uploads.Done()
+
+	err := server.imageStore.GenerateVariants(server.variantsCtx, imageID)
 	if err != nil {
-		return logError(status.Errorf(codes.Internal, "cannot save image to the store: %v", err))
+		slog.Warn("cannot generate variants", "image_id", imageID, "error", err)
 	}
-	res := &pb.UploadImageResponse{
-		Id:   imageID,
-		Size: uint32(imageSize),
+}
+
+func (server *ImageServer) DeleteImage(ctx context.Context, req *pb.DeleteImageRequest) (*pb.DeleteImageResponse, error) {
+	slog.DebugContext(ctx, "delete image", "image_id", req.GetId())
+	if err := server.checkAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	err := server.imageStore.Delete(req.GetId())
+	if errors.Is(err, ErrImageNotFound) {
+		return nil, status.Errorf(codes.NotFound, "cannot find image: %v", err)
 	}
-	err = stream.SendAndClose(res)
 	if err != nil {
-		return logError(status.Errorf(codes.Unknown, "cannot send response: %v", err))
+		return nil, status.Errorf(codes.Internal, "cannot delete image: %v", err)
+	}
+
+	slog.InfoContext(ctx, "moved image to the trash", "image_id", req.GetId())
+	return &pb.DeleteImageResponse{
+		Id: req.GetId(),
+	}, nil
+}
+
+func (server *ImageServer) RestoreImage(ctx context.Context, req *pb.RestoreImageRequest) (*pb.RestoreImageResponse, error) {
+	slog.DebugContext(ctx, "restore image", "image_id", req.GetId())
+	if err := server.checkAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	err := server.imageStore.Restore(req.GetId())
+	switch {
+	case errors.Is(err, ErrImageNotFound):
+		return nil, status.Errorf(codes.NotFound, "cannot find image: %v", err)
+	case errors.Is(err, ErrImageNotDeleted):
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot restore image: %v", err)
+	case errors.Is(err, ErrImageExists):
+		return nil, status.Errorf(codes.AlreadyExists, "cannot restore image: %v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "cannot restore image: %v", err)
+	}
+
+	slog.InfoContext(ctx, "restored image from the trash", "image_id", req.GetId())
+	return &pb.RestoreImageResponse{
+		Id: req.GetId(),
+	}, nil
+}
+
+func (server *ImageServer) GetImageInfo(ctx context.Context, req *pb.GetImageInfoRequest) (*pb.ImageFullInfo, error) {
+	if err := server.readAdmission.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer server.readAdmission.release()
+
+	imageFullInfo, err := server.imageStore.GetImageInfo(req.GetId())
+	if errors.Is(err, ErrImageNotFound) {
+		return nil, status.Errorf(codes.NotFound, "cannot find image: %v", err)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot get image info: %v", err)
+	}
+	if !canAccess(ctx, imageFullInfo.GetOwner()) {
+		return nil, status.Errorf(codes.NotFound, "cannot find image: %v", ErrImageNotFound)
+	}
+	return imageFullInfo, nil
+}
+
+func (server *ImageServer) RenameImage(ctx context.Context, req *pb.RenameImageRequest) (*pb.RenameImageResponse, error) {
+	slog.DebugContext(ctx, "rename image", "image_id", req.GetId(), "image_name", req.GetImageName())
+	if err := server.checkAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	info, err := server.imageStore.Rename(req.GetId(), req.GetImageName())
+	switch {
+	case errors.Is(err, ErrImageNotFound):
+		return nil, status.Errorf(codes.NotFound, "cannot find image: %v", err)
+	case errors.Is(err, ErrInvalidImageName):
+		return nil, status.Errorf(codes.InvalidArgument, "cannot rename image: %v", err)
+	case errors.Is(err, ErrImageExists):
+		return nil, status.Errorf(codes.AlreadyExists, "cannot rename image: %v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "cannot rename image: %v", err)
+	}
+
+	slog.InfoContext(ctx, "renamed image", "image_id", info.ID, "image_name", info.Name)
+	return &pb.RenameImageResponse{
+		Id:        info.ID,
+		ImageName: info.Name,
+	}, nil
+}
+
+// GetServerInfo returns the limits of the server, so that clients can size their concurrency to them
+func (server *ImageServer) GetServerInfo(ctx context.Context, req *pb.Empty) (*pb.ServerInfo, error) {
+	return &pb.ServerInfo{
+		MaxReadConns:   server.maxReadConns,
+		MaxStreamConns: server.maxStreamConns,
+		MaxImageSize:   server.maxImageSize,
+		Admission: []*pb.AdmissionStats{
+			server.readAdmission.stats(),
+			server.uploadAdmission.stats(),
+			server.downloadAdmission.stats(),
+			server.uploadBytes.stats(),
+		},
+	}, nil
+}
+
+// beginUpload counts a new upload stream in, it is refused once the server is draining
+func (server *ImageServer) beginUpload() error {
+	server.drainMutex.Lock()
+	defer server.drainMutex.Unlock()
+
+	if server.draining {
+		return status.Error(codes.Unavailable, "cannot upload image: server is shutting down")
 	}
-	log.Printf("saved image with name: %s, size: %d", imageName, imageSize)
+	server.uploads.Add(1)
 	return nil
 }
 
-func contextError(ctx context.Context) error {
-	switch ctx.Err() {
-	case context.Canceled:
-		return logError(status.Error(codes.Canceled, "request is canceled"))
-	case context.DeadlineExceeded:
-		return logError(status.Error(codes.DeadlineExceeded, "deadline is exceeded"))
+func (server *ImageServer) isDraining() bool {
+	server.drainMutex.Lock()
+	defer server.drainMutex.Unlock()
+
+	return server.draining
+}
+
+// Drain makes the server refuse new uploads, the ones in flight carry on
+func (server *ImageServer) Drain() {
+	server.drainMutex.Lock()
+	defer server.drainMutex.Unlock()
+
+	server.draining = true
+}
+
+// Wait blocks until the uploads in flight and the variant generation they started are done.
+// Once ctx ends the variant generation is cancelled, the variants are generated on first request instead.
+// It must only be called after Drain.
+func (server *ImageServer) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		server.uploads.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	slog.Warn("variant generation still running, cancelling it")
+	server.cancelVariants()
+	<-done
+}
+
+// receiveError keeps the code of a failed Recv, such as Canceled or DeadlineExceeded, and adds the cause to the message
+func receiveError(what string, err error) error {
+	var st *status.Status
+	switch {
+	case err == io.EOF:
+		return status.Errorf(codes.InvalidArgument, "cannot receive %s: stream ended early", what)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		st = status.FromContextError(err)
 	default:
-		return nil
+		st = status.Convert(err)
 	}
+	return status.Errorf(st.Code(), "cannot receive %s: %v", what, st.Message())
 }
 
-func logError(err error) error {
-	if err != nil {
-		log.Print(err)
+// saveImageError maps the errors of ImageStore.Save to status errors
+func saveImageError(err error) error {
+	switch {
+	case errors.Is(err, ErrImageExists):
+		return status.Errorf(codes.AlreadyExists, "cannot save image to the store: %v", err)
+	case errors.Is(err, ErrInvalidImageName):
+		return status.Errorf(codes.InvalidArgument, "cannot save image to the store: %v", err)
+	default:
+		return status.Errorf(codes.Internal, "cannot save image to the store: %v", err)
 	}
-	return err
 }
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReceiveError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantText string
+	}{
+		{"end of stream", io.EOF, codes.InvalidArgument, "stream ended early"},
+		{"cancelled context", fmt.Errorf("recv: %w", context.Canceled), codes.Canceled, "context canceled"},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded, "deadline exceeded"},
+		{"cancelled stream", status.Error(codes.Canceled, "client went away"), codes.Canceled, "client went away"},
+		{"other error", errors.New("connection reset"), codes.Unknown, "connection reset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(receiveError("image info", tt.err))
+			if st.Code() != tt.wantCode {
+				t.Errorf("got code %s, want %s", st.Code(), tt.wantCode)
+			}
+			if !strings.HasPrefix(st.Message(), "cannot receive image info: ") || !strings.Contains(st.Message(), tt.wantText) {
+				t.Errorf("got message %q, want the cause %q", st.Message(), tt.wantText)
+			}
+		})
+	}
+}
+
+// newTestImageServer returns a server over a store in a temporary folder, with room for images of up to 1MiB
+func newTestImageServer(t *testing.T) (*ImageServer, *DiskImageStore) {
+	t.Helper()
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	server := NewImageServer(store, nil, ImageServerLimits{
+		MaxReadConns:     10,
+		MaxStreamConns:   10,
+		MaxImageSize:     1 << 20,
+		UploadByteBudget: 1 << 20,
+		MaxQueueWait:     time.Second,
+		AllowedFormats:   ImageFormatNames(),
+	})
+	t.Cleanup(func() { server.Wait(context.Background()) })
+	return server, store
+}
+
+func TestReceiveImageDeclaredSize(t *testing.T) {
+	server, _ := newTestImageServer(t)
+	data := encodePNG(t, 10, 10)
+	ctx := context.Background()
+
+	// a declared size takes only that many bytes, the rest of the budget stays free for other uploads
+	reader := &blockingReader{r: bytes.NewReader(data), started: make(chan struct{}), proceed: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		_, err := server.receiveImage(ctx, ImageMeta{Name: "a.png", Size: int64(len(data))}, reader)
+		done <- err
+	}()
+	<-reader.started
+	if running := server.uploadBytes.running.Load(); running != int64(len(data)) {
+		t.Errorf("upload holds %d bytes of the budget, want the declared %d", running, len(data))
+	}
+	close(reader.proceed)
+	if err := <-done; err != nil {
+		t.Fatalf("receiveImage: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		size int64
+	}{
+		{"smaller than the image", int64(len(data)) - 1},
+		{"larger than the largest image", server.maxImageSize + 1},
+		{"negative", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.receiveImage(ctx, ImageMeta{Name: "b.png", Size: tt.size}, bytes.NewReader(data))
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("got %v, want InvalidArgument", err)
+			}
+		})
+	}
+	if running := server.uploadBytes.running.Load(); running != 0 {
+		t.Errorf("budget holds %d bytes after the uploads", running)
+	}
+}
+
+// blockingReader signals started on the first read and waits for proceed before it reads anything
+type blockingReader struct {
+	r       io.Reader
+	started chan struct{}
+	proceed chan struct{}
+	once    bool
+}
+
+func (reader *blockingReader) Read(p []byte) (int, error) {
+	if !reader.once {
+		reader.once = true
+		close(reader.started)
+		<-reader.proceed
+	}
+	return reader.r.Read(p)
+}
+
+// stalledVariantStore generates variants only once its context ends
+type stalledVariantStore struct {
+	*DiskImageStore
+	started chan struct{}
+}
+
+func (store *stalledVariantStore) GenerateVariants(ctx context.Context, imageID string) error {
+	close(store.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWaitCancelsVariantGeneration(t *testing.T) {
+	_, diskStore := newTestImageServer(t)
+	store := &stalledVariantStore{DiskImageStore: diskStore, started: make(chan struct{})}
+	server := NewImageServer(store, nil, ImageServerLimits{
+		MaxImageSize:     1 << 20,
+		UploadByteBudget: 1 << 20,
+		AllowedFormats:   ImageFormatNames(),
+	})
+
+	_, err := server.receiveImage(context.Background(), ImageMeta{Name: "a.png"}, bytes.NewReader(encodePNG(t, 10, 10)))
+	if err != nil {
+		t.Fatalf("receiveImage: %v", err)
+	}
+	<-store.started
+
+	server.Drain()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	waited := make(chan struct{})
+	go func() {
+		server.Wait(ctx)
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait does not return once its context ends")
+	}
+}
+
+func TestGenerateVariantsStopsWithContext(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, map[string]int{"thumbnail": 16}, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+	info, err := store.Save(context.Background(), ImageMeta{Name: "a.png"}, bytes.NewReader(encodePNG(t, 100, 50)))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := store.GenerateVariants(ctx, info.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateVariants: got %v, want context.Canceled", err)
+	}
+	if err := store.generateVariant(ctx, info, "thumbnail", 16); !errors.Is(err, context.Canceled) {
+		t.Errorf("generateVariant: got %v, want context.Canceled", err)
+	}
+	if _, err := store.findVariant(info.ID, "thumbnail"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("variant of a cancelled generation: got %v, want none", err)
+	}
+}
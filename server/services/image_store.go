@@ -1,96 +1,645 @@
 package services
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/navruz-rakhimov/tages-project/protos"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
 )
 
 type ImageStore interface {
-	Save(imageName string, imageType string, imageData bytes.Buffer) (string, error)
-	GetImagesInfoList() ([]*protos.ImageFullInfo, error)
+	Save(ctx context.Context, meta ImageMeta, imageData io.Reader) (*ImageInfo, error)
+	GetImagesInfoList(query ImageQuery) ([]*protos.ImageFullInfo, string, error)
+	Open(imageID string) (*ImageInfo, io.ReadSeekCloser, error)
+	OpenByName(owner string, imageName string) (*ImageInfo, io.ReadSeekCloser, error)
+	Delete(imageID string) error
+	Restore(imageID string) error
+	Rename(imageID string, imageName string) (*ImageInfo, error)
+	GetImageInfo(imageID string) (*protos.ImageFullInfo, error)
+	OpenVariant(imageID string, variant string) (*ImageInfo, io.ReadSeekCloser, error)
+	GenerateVariants(ctx context.Context, imageID string) error
+	// Check returns an error if the store cannot take images right now
+	Check() error
 }
 
+const (
+	timeLayout = "2006-01-02 15:04:05"
+	// uploadsFolderName holds the images that are still being received
+	uploadsFolderName = ".uploads"
+)
+
+var (
+	// ErrImageNotFound is returned when the requested image is not in the store
+	ErrImageNotFound = errors.New("image not found")
+	// ErrImageNotDeleted is returned when restoring an image that is not in the trash
+	ErrImageNotDeleted = errors.New("image is not deleted")
+	// ErrImageExists is returned when another image of the owner already takes the name
+	ErrImageExists = errors.New("image already exists")
+)
+
+// DiskImageStore keeps images as files in a folder.
+// Entries of the images map are never modified in place, changes replace them with a copy.
 type DiskImageStore struct {
-	mutex       sync.RWMutex
-	imageFolder string
-	images      map[string]*ImageInfo
+	mutex          sync.RWMutex
+	imageFolder    string
+	images         map[string]*ImageInfo
+	views          map[SortOrder][]*ImageInfo
+	index          *imageIndex
+	trashRetention time.Duration
+	variants       map[string]int
+	variantGroup   singleflight.Group
+	// maxVariantPixels bounds the images that are decoded to generate variants
+	maxVariantPixels int64
+	stopPurger       chan struct{}
+	purgerDone       chan struct{}
+	// dedup stores the content of new images in blobs, see NewContentImageStore
+	dedup bool
+	// blobRefs counts the images that refer to every blob, in the trash or not
+	blobRefs map[string]int
+	// names maps the owner and the name of every image that is not deleted to its id
+	names map[nameKey]string
+}
+
+// NewDiskImageStore loads the image index from the image folder and reconciles it with the files.
+// Deleted images are kept in the trash for trashRetention before they are purged.
+// Variants maps the name of every resized variant to the maximum length of its longer side,
+// they are only generated for images of at most maxVariantPixels pixels, or of any size if it is 0.
+func NewDiskImageStore(imageFolder string, trashRetention time.Duration, variants map[string]int, maxVariantPixels int64) (*DiskImageStore, error) {
+	return openDiskImageStore(imageFolder, trashRetention, variants, maxVariantPixels, false)
 }
 
-func NewDiskImageStore(imageFolder string) *DiskImageStore {
-	return &DiskImageStore{
-		imageFolder: imageFolder,
-		images:      make(map[string]*ImageInfo),
+// NewContentImageStore is NewDiskImageStore with content addressed storage.
+// The content of identical images is stored once, in a blob named by its SHA-256,
+// and shared by all the image ids and names that refer to it.
+// Images stored under their id by a plain store are moved into blobs when it opens.
+func NewContentImageStore(imageFolder string, trashRetention time.Duration, variants map[string]int, maxVariantPixels int64) (*DiskImageStore, error) {
+	return openDiskImageStore(imageFolder, trashRetention, variants, maxVariantPixels, true)
+}
+
+func openDiskImageStore(imageFolder string, trashRetention time.Duration, variants map[string]int, maxVariantPixels int64, dedup bool) (*DiskImageStore, error) {
+	for _, folder := range []string{trashFolderName, variantsFolderName, blobsFolderName} {
+		err := os.MkdirAll(filepath.Join(imageFolder, folder), 0755)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create image folder: %w", err)
+		}
+	}
+
+	// uploads interrupted by a crash cannot be resumed
+	err := os.RemoveAll(filepath.Join(imageFolder, uploadsFolderName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot clean up unfinished uploads: %w", err)
+	}
+	err = os.Mkdir(filepath.Join(imageFolder, uploadsFolderName), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create uploads folder: %w", err)
+	}
+
+	index, images, err := openImageIndex(imageFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &DiskImageStore{
+		imageFolder:      imageFolder,
+		images:           images,
+		index:            index,
+		trashRetention:   trashRetention,
+		variants:         variants,
+		maxVariantPixels: maxVariantPixels,
+		stopPurger:       make(chan struct{}),
+		purgerDone:       make(chan struct{}),
+		dedup:            dedup,
+		blobRefs:         make(map[string]int),
+	}
+	store.indexNames()
+
+	err = store.reconcile()
+	if err != nil {
+		index.close()
+		return nil, err
 	}
+
+	if dedup {
+		err = store.migrateToBlobs()
+		if err != nil {
+			index.close()
+			return nil, err
+		}
+	}
+
+	err = index.compact(store.images)
+	if err != nil {
+		index.close()
+		return nil, err
+	}
+
+	go store.runPurger()
+
+	return store, nil
 }
 
-func (store *DiskImageStore) Save(imageName string, imageType string, imageData bytes.Buffer) (string, error) {
-	imageID, err := uuid.NewRandom()
+// reconcile drops index entries whose files are gone and indexes files that have no entry.
+// Images saved under their name by earlier versions are moved to the sharded layout.
+func (store *DiskImageStore) reconcile() error {
+	for imageID, info := range store.images {
+		if info.Blob != "" {
+			info.Path = store.blobPath(info.Blob)
+		} else if info.DeletedAt != nil {
+			info.Path = store.trashPath(imageID)
+		} else {
+			info.Path = store.imagePath(imageID)
+			if info.Name == filepath.Base(info.Name) && !strings.HasPrefix(info.Name, ".") {
+				err := store.migrateImage(filepath.Join(store.imageFolder, info.Name), info.Path)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := os.Stat(info.Path); err != nil {
+			slog.Warn("dropping image from the index", "image_id", imageID, "error", err)
+			store.removeImage(imageID)
+		}
+	}
+
+	err := store.reconcileTrash()
 	if err != nil {
-		return "", fmt.Errorf("cannot generate image id: %w", err)
+		return err
 	}
 
-	imagePath := fmt.Sprintf("%s/%s", store.imageFolder, imageName)
+	err = store.reconcileBlobs()
+	if err != nil {
+		return err
+	}
+
+	err = store.reconcileVariants()
+	if err != nil {
+		return err
+	}
 
-	file, err := os.Create(imagePath)
+	err = store.reconcileShards()
 	if err != nil {
-		return "", fmt.Errorf("cannot create image file: %w", err)
+		return err
 	}
 
-	_, err = imageData.WriteTo(file)
+	fileInfos, err := ioutil.ReadDir(store.imageFolder)
 	if err != nil {
-		return "", fmt.Errorf("cannot write image to file: %w", err)
+		return fmt.Errorf("cannot read dir: %w", err)
 	}
 
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.Mode().IsRegular() || strings.HasPrefix(fileInfo.Name(), ".") {
+			continue
+		}
+
+		imageID, err := uuid.NewRandom()
+		if err != nil {
+			return fmt.Errorf("cannot generate image id: %w", err)
+		}
+
+		imageName, err := sanitizeImageName(fileInfo.Name())
+		if err != nil {
+			imageName = imageID.String()
+		}
+		// adopted images have no owner
+		if store.findByName("", imageName) != nil {
+			imageName = store.uniqueName("", imageName)
+		}
+
+		imagePath := store.imagePath(imageID.String())
+		err = store.migrateImage(filepath.Join(store.imageFolder, fileInfo.Name()), imagePath)
+		if err != nil {
+			return err
+		}
+
+		err = store.adoptImage(imageID.String(), imageName, imagePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcileShards indexes the files of the sharded layout that have no entry,
+// such as an image whose save was interrupted before it got into the index
+func (store *DiskImageStore) reconcileShards() error {
+	for _, imagePath := range store.shardFiles() {
+		imageID := filepath.Base(imagePath)
+		if _, ok := store.images[imageID]; ok {
+			continue
+		}
+		if _, err := uuid.Parse(imageID); err != nil || imagePath != store.imagePath(imageID) {
+			slog.Warn("ignoring unknown file", "path", imagePath)
+			continue
+		}
+
+		err := store.adoptImage(imageID, imageID, imagePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shardFiles lists the files two shard folders deep, skipping the hidden folders
+func (store *DiskImageStore) shardFiles() []string {
+	var paths []string
+	shards, _ := os.ReadDir(store.imageFolder)
+	for _, shard := range shards {
+		if !shard.IsDir() || strings.HasPrefix(shard.Name(), ".") {
+			continue
+		}
+		subShards, _ := os.ReadDir(filepath.Join(store.imageFolder, shard.Name()))
+		for _, subShard := range subShards {
+			if !subShard.IsDir() {
+				continue
+			}
+			entries, _ := os.ReadDir(filepath.Join(store.imageFolder, shard.Name(), subShard.Name()))
+			for _, entry := range entries {
+				if entry.Type().IsRegular() {
+					paths = append(paths, filepath.Join(store.imageFolder, shard.Name(), subShard.Name(), entry.Name()))
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// migrateImage moves an image file from its old location into the sharded layout,
+// it does nothing if there is no file at the old location
+func (store *DiskImageStore) migrateImage(oldPath string, imagePath string) error {
+	fileInfo, err := os.Lstat(oldPath)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && !fileInfo.Mode().IsRegular()) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot stat image file: %w", err)
+	}
+	if _, err := os.Stat(imagePath); err == nil {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(imagePath), 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create image folder: %w", err)
+	}
+	err = os.Rename(oldPath, imagePath)
+	if err != nil {
+		return fmt.Errorf("cannot move image file into place: %w", err)
+	}
+
+	slog.Info("moved image", "from", oldPath, "to", imagePath)
+	return nil
+}
+
+// adoptImage indexes an image file that was found on disk
+func (store *DiskImageStore) adoptImage(imageID string, imageName string, imagePath string) error {
+	fileInfo, err := os.Stat(imagePath)
+	if err != nil {
+		return fmt.Errorf("cannot stat image file: %w", err)
+	}
+
+	checksum, err := fileChecksum(imagePath)
+	if err != nil {
+		return err
+	}
+
+	// not every file system records a birth time, the modification time is the next best thing
+	createdAt, ok := fileBirthTime(imagePath, fileInfo)
+	if !ok {
+		createdAt = fileInfo.ModTime()
+	}
+
+	meta := sniffImageFile(imagePath)
+	store.putImage(&ImageInfo{
+		ID:        imageID,
+		Name:      imageName,
+		Type:      filepath.Ext(imageName),
+		MimeType:  meta.MimeType,
+		Width:     meta.Width,
+		Height:    meta.Height,
+		Size:      fileInfo.Size(),
+		Checksum:  checksum,
+		CreatedAt: createdAt.UTC(),
+		UpdatedAt: fileInfo.ModTime().UTC(),
+		Path:      imagePath,
+	})
+	slog.Info("indexed existing image", "image_id", imageID, "image_name", imageName)
+	return nil
+}
+
+// Save streams the image data into a temporary file and moves it into place once all of it is written.
+// If reading the data fails nothing is left behind in the image folder.
+// The file is stored under the image id, or in the blob of its content if the store deduplicates,
+// the sanitised name is kept as metadata only and the conflict policy of meta applies when another image of the owner already has it.
+func (store *DiskImageStore) Save(ctx context.Context, meta ImageMeta, imageData io.Reader) (info *ImageInfo, err error) {
+	ctx, span := tracer.Start(ctx, "DiskImageStore.Save")
+	defer func() { endSpan(span, err) }()
+
+	imageName, err := sanitizeImageName(meta.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	imageID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate image id: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Join(store.imageFolder, uploadsFolderName), imageID.String()+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create image file: %w", err)
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	imageSize, checksum, err := writeImageFile(ctx, file, imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	info = &ImageInfo{
+		ID:        imageID.String(),
+		Name:      imageName,
+		Type:      meta.Type,
+		MimeType:  meta.MimeType,
+		Width:     meta.Width,
+		Height:    meta.Height,
+		Size:      imageSize,
+		Checksum:  checksum,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Owner:     meta.Owner,
+		Path:      store.imagePath(imageID.String()),
+	}
+
+	// waiting for the lock is part of the commit, saves of the same name are serialised here
+	_, commitSpan := tracer.Start(ctx, "commit image")
+	defer func() { endSpan(commitSpan, err) }()
+
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
-	store.images[imageID.String()] = &ImageInfo{
-		Type: imageType,
-		Path: imagePath,
+	var replaced *ImageInfo
+	if existing := store.findByName(meta.Owner, imageName); existing != nil {
+		switch meta.ConflictPolicy {
+		case protos.ConflictPolicy_OVERWRITE:
+			// the image keeps its id, only the content is replaced
+			info.ID = existing.ID
+			info.CreatedAt = existing.CreatedAt
+			info.Path = store.imagePath(existing.ID)
+			replaced = existing
+			err = store.removeVariants(existing.ID)
+			if err != nil {
+				return nil, err
+			}
+		case protos.ConflictPolicy_AUTO_RENAME:
+			info.Name = store.uniqueName(meta.Owner, imageName)
+		default:
+			return nil, fmt.Errorf("cannot save image as %s: %w", imageName, ErrImageExists)
+		}
+	}
+
+	deduplicated := false
+	if store.dedup {
+		info.Blob = checksum
+		info.Path = store.blobPath(checksum)
+		deduplicated, err = store.storeBlob(tmpPath, checksum)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = os.MkdirAll(filepath.Dir(info.Path), 0755)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create image folder: %w", err)
+		}
+
+		err = os.Rename(tmpPath, info.Path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot move image file into place: %w", err)
+		}
+	}
+
+	err = store.index.put(info)
+	if err != nil {
+		return nil, err
+	}
+	store.putImage(info)
+	store.compactIndex()
+	if info.Blob != "" {
+		store.blobRefs[info.Blob]++
+	}
+
+	// the overwritten content is only removed once nothing refers to it anymore
+	if replaced != nil && (replaced.Blob != "" || replaced.Path != info.Path) {
+		err = store.removeContent(replaced)
+		if err != nil {
+			slog.WarnContext(ctx, "cannot remove overwritten image content", "image_id", info.ID, "error", err)
+		}
 	}
 
-	return imageID.String(), nil
+	saved := *info
+	saved.Deduplicated = deduplicated
+	return &saved, nil
 }
 
-func (store *DiskImageStore) GetImagesInfoList() ([]*protos.ImageFullInfo, error) {
-	var (
-		imageInfoList []*protos.ImageFullInfo
-		createdAt     string
-		updatedAt     string
-	)
+// writeImageFile copies the image data into the temporary file and syncs it to disk.
+// It returns the size and checksum of the data and closes the file in any case.
+func writeImageFile(ctx context.Context, file *os.File, imageData io.Reader) (imageSize int64, checksum string, err error) {
+	ctx, span := tracer.Start(ctx, "write image file")
+	defer func() {
+		span.SetAttributes(attribute.Int64("image.size", imageSize))
+		endSpan(span, err)
+	}()
 
-	fileInfos, err := ioutil.ReadDir(store.imageFolder)
+	hash := sha256.New()
+	imageSize, err = io.Copy(io.MultiWriter(file, hash), imageData)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read dir: %w", err)
+		file.Close()
+		return imageSize, "", fmt.Errorf("cannot write image to file: %w", err)
 	}
 
-	for _, fileInfo := range fileInfos {
-		var fileTime = fileInfo.Sys().(*syscall.Win32FileAttributeData)
-		var cTime = time.Unix(0, fileTime.CreationTime.Nanoseconds())
-		var uTime = time.Unix(0, fileTime.LastAccessTime.Nanoseconds())
+	// temporary files are created private, images get the usual permissions
+	err = file.Chmod(0644)
+	if err != nil {
+		file.Close()
+		return imageSize, "", fmt.Errorf("cannot change image file mode: %w", err)
+	}
+
+	_, syncSpan := tracer.Start(ctx, "sync image file")
+	err = file.Sync()
+	endSpan(syncSpan, err)
+	if err != nil {
+		file.Close()
+		return imageSize, "", fmt.Errorf("cannot sync image file: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return imageSize, "", fmt.Errorf("cannot close image file: %w", err)
+	}
+	return imageSize, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// compactIndex compacts the journal once it holds many more records than there are images,
+// so that it does not grow for as long as the server runs. The caller must hold the lock.
+func (store *DiskImageStore) compactIndex() {
+	if !store.index.needsCompaction(len(store.images)) {
+		return
+	}
+	err := store.index.compact(store.images)
+	if err != nil {
+		slog.Warn("cannot compact the index", "error", err)
+	}
+}
+
+// Check returns an error unless the index is open and a file can be written to the image folder
+func (store *DiskImageStore) Check() error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	err := store.index.check()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Join(store.imageFolder, uploadsFolderName), "check-*")
+	if err != nil {
+		return fmt.Errorf("image folder is not writable: %w", err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// Close stops the trash purger, removes what is left of unfinished uploads
+// and compacts the index journal before closing it.
+// Saves must have returned before the store is closed.
+func (store *DiskImageStore) Close() error {
+	close(store.stopPurger)
+	<-store.purgerDone
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := removeFolderContents(filepath.Join(store.imageFolder, uploadsFolderName))
+	if err != nil {
+		slog.Warn("cannot clean up unfinished uploads", "error", err)
+	}
+
+	err = store.index.compact(store.images)
+	if err != nil {
+		store.index.close()
+		return err
+	}
+	return store.index.close()
+}
+
+func removeFolderContents(folder string) error {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := os.RemoveAll(filepath.Join(folder, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns the info and the content of the image saved under the given id
+func (store *DiskImageStore) Open(imageID string) (*ImageInfo, io.ReadSeekCloser, error) {
+	store.mutex.RLock()
+	info, ok := store.images[imageID]
+	store.mutex.RUnlock()
+	if !ok || info.DeletedAt != nil {
+		return nil, nil, ErrImageNotFound
+	}
+
+	return openImage(info)
+}
 
-		createdAt = cTime.Format("2006-01-02 15:04:05")
-		updatedAt = uTime.Format("2006-01-02 15:04:05")
+// OpenByName returns the info and the content of the image of the owner with the given name
+func (store *DiskImageStore) OpenByName(owner string, imageName string) (*ImageInfo, io.ReadSeekCloser, error) {
+	store.mutex.RLock()
+	info := store.findByName(owner, imageName)
+	store.mutex.RUnlock()
+	if imageName == "" || info == nil {
+		return nil, nil, ErrImageNotFound
+	}
+
+	return openImage(info)
+}
+
+// imagePath shards the image files by the first two pairs of id characters,
+// so that no folder gets too many entries
+func (store *DiskImageStore) imagePath(imageID string) string {
+	return filepath.Join(store.imageFolder, imageID[0:2], imageID[2:4], imageID)
+}
 
-		imageInfoList = append(imageInfoList, &protos.ImageFullInfo{
-			ImageName: fileInfo.Name(),
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
-		})
+func openImage(info *ImageInfo) (*ImageInfo, io.ReadSeekCloser, error) {
+	file, err := os.Open(info.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open image file: %w", err)
 	}
 
-	return imageInfoList, nil
+	return info, file, nil
 }
 
+// ImageInfo is the metadata the store keeps for every image
 type ImageInfo struct {
-	Type string
-	Path string
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	MimeType  string     `json:"mime_type,omitempty"`
+	Width     int        `json:"width,omitempty"`
+	Height    int        `json:"height,omitempty"`
+	Size      int64      `json:"size"`
+	Checksum  string     `json:"checksum"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Owner is the subject that uploaded the image, empty for images stored without authentication
+	Owner string `json:"owner,omitempty"`
+	// Blob is the hash of the blob the content is stored in, empty for images stored under their id
+	Blob string `json:"blob,omitempty"`
+	Path string `json:"-"`
+	// Deduplicated is set by Save when the content was already stored for another image
+	Deduplicated bool `json:"-"`
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot open image file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", fmt.Errorf("cannot read image file: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	trashFolderName    = ".trash"
+	trashPurgeInterval = time.Hour
+)
+
+// Delete moves the image to the trash, it can be restored until the trash retention runs out.
+// The blob of a content addressed image stays where it is, the image in the trash keeps referring to it.
+func (store *DiskImageStore) Delete(imageID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	info, ok := store.images[imageID]
+	if !ok || info.DeletedAt != nil {
+		return ErrImageNotFound
+	}
+
+	deleted := *info
+	deletedAt := time.Now().UTC()
+	deleted.DeletedAt = &deletedAt
+	if info.Blob == "" {
+		deleted.Path = store.trashPath(imageID)
+		err := os.Rename(info.Path, deleted.Path)
+		if err != nil {
+			return fmt.Errorf("cannot move image to the trash: %w", err)
+		}
+	}
+
+	err := store.index.put(&deleted)
+	if err != nil {
+		if info.Blob == "" {
+			os.Rename(deleted.Path, info.Path)
+		}
+		return err
+	}
+	store.putImage(&deleted)
+	store.compactIndex()
+
+	return nil
+}
+
+// Restore moves the image from the trash back, unless another image has taken its name in the meantime
+func (store *DiskImageStore) Restore(imageID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	info, ok := store.images[imageID]
+	if !ok {
+		return ErrImageNotFound
+	}
+	if info.DeletedAt == nil {
+		return ErrImageNotDeleted
+	}
+
+	restored := *info
+	restored.DeletedAt = nil
+
+	if store.findByName(info.Owner, info.Name) != nil {
+		return fmt.Errorf("cannot restore image as %s: %w", info.Name, ErrImageExists)
+	}
+
+	if info.Blob == "" {
+		restored.Path = store.imagePath(imageID)
+		err := os.MkdirAll(filepath.Dir(restored.Path), 0755)
+		if err != nil {
+			return fmt.Errorf("cannot create image folder: %w", err)
+		}
+
+		err = os.Rename(info.Path, restored.Path)
+		if err != nil {
+			return fmt.Errorf("cannot move image out of the trash: %w", err)
+		}
+	}
+
+	err := store.index.put(&restored)
+	if err != nil {
+		if info.Blob == "" {
+			os.Rename(restored.Path, info.Path)
+		}
+		return err
+	}
+	store.putImage(&restored)
+	store.compactIndex()
+
+	return nil
+}
+
+// PurgeTrash removes the images that were deleted before the given time for good,
+// with the blobs that no other image refers to.
+// The index entry goes first, content left behind by a failure is cleaned up when the store opens next.
+// An image that cannot be purged does not stop the others, their errors are returned together.
+func (store *DiskImageStore) PurgeTrash(deletedBefore time.Time) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	purged := 0
+	var errs []error
+	for imageID, info := range store.images {
+		if info.DeletedAt == nil || !info.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		err := store.index.remove(imageID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot purge image %s: %w", imageID, err))
+			continue
+		}
+		store.removeImage(imageID)
+		purged++
+
+		err = store.removeContent(info)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot purge image %s: %w", imageID, err))
+		}
+		err = store.removeVariants(imageID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot purge image %s: %w", imageID, err))
+		}
+	}
+	store.compactIndex()
+
+	return purged, errors.Join(errs...)
+}
+
+func (store *DiskImageStore) runPurger() {
+	defer close(store.purgerDone)
+
+	interval := trashPurgeInterval
+	if store.trashRetention > 0 && store.trashRetention < interval {
+		interval = store.trashRetention
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-store.stopPurger:
+			return
+		case <-ticker.C:
+			purged, err := store.PurgeTrash(time.Now().Add(-store.trashRetention))
+			if err != nil {
+				slog.Error("cannot purge the trash", "error", err)
+			}
+			if purged > 0 {
+				slog.Info("purged the trash", "images", purged)
+			}
+		}
+	}
+}
+
+// reconcileTrash removes files from the trash that no deleted image refers to
+func (store *DiskImageStore) reconcileTrash() error {
+	fileInfos, err := ioutil.ReadDir(filepath.Join(store.imageFolder, trashFolderName))
+	if err != nil {
+		return fmt.Errorf("cannot read trash dir: %w", err)
+	}
+
+	for _, fileInfo := range fileInfos {
+		info, ok := store.images[fileInfo.Name()]
+		if ok && info.DeletedAt != nil {
+			continue
+		}
+
+		slog.Warn("removing unknown file from the trash", "name", fileInfo.Name())
+		err := os.RemoveAll(filepath.Join(store.imageFolder, trashFolderName, fileInfo.Name()))
+		if err != nil {
+			return fmt.Errorf("cannot remove file from the trash: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (store *DiskImageStore) trashPath(imageID string) string {
+	return filepath.Join(store.imageFolder, trashFolderName, imageID)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurgeTrashContinuesPastFailures(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, nil, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	var ids []string
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		info, err := store.Save(context.Background(), ImageMeta{Name: name}, strings.NewReader(name))
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		err = store.Delete(info.ID)
+		if err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		ids = append(ids, info.ID)
+	}
+
+	// a non-empty folder in place of the trash file cannot be removed
+	broken := store.trashPath(ids[1])
+	if err := os.Remove(broken); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(broken, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := store.PurgeTrash(time.Now().Add(time.Minute))
+	if err == nil {
+		t.Error("got no error for the image that cannot be removed")
+	}
+	if purged != len(ids) {
+		t.Errorf("purged %d images, want %d", purged, len(ids))
+	}
+	for _, id := range ids {
+		if _, err := store.GetImageInfo(id); err == nil {
+			t.Errorf("image %s is still in the index", id)
+		}
+	}
+	for _, id := range []string{ids[0], ids[2]} {
+		if _, err := os.Stat(store.trashPath(id)); !os.IsNotExist(err) {
+			t.Errorf("trash file of %s is still there", id)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// ErrInvalidImage is returned when the uploaded data is not an allowed image of the declared type
+var ErrInvalidImage = errors.New("invalid image")
+
+// imageFormat is an image format that can be uploaded
+type imageFormat struct {
+	mimeType   string
+	extensions []string
+}
+
+// imageFormats are the formats images can be uploaded in,
+// keyed by the format names of the image package decoders
+var imageFormats = map[string]imageFormat{
+	"jpeg": {mimeType: "image/jpeg", extensions: []string{".jpg", ".jpeg"}},
+	"png":  {mimeType: "image/png", extensions: []string{".png"}},
+	"gif":  {mimeType: "image/gif", extensions: []string{".gif"}},
+}
+
+// ImageMeta describes an image that is being saved
+type ImageMeta struct {
+	Name     string
+	Type     string
+	MimeType string
+	Width    int
+	Height   int
+	// ConflictPolicy applies when another image already has the name
+	ConflictPolicy ConflictPolicy
+	// Owner is recorded with the saved image
+	Owner string
+	// Size is the number of bytes the upload declares, 0 if it does not.
+	// An image that turns out to be larger is rejected.
+	Size int64
+}
+
+// ImageFormatNames returns the names of the formats images can be uploaded in
+func ImageFormatNames() []string {
+	names := make([]string, 0, len(imageFormats))
+	for name := range imageFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sniffImage checks the magic bytes and decodes the header of the image data.
+// It fills in the detected MIME type and dimensions of meta and returns a reader
+// that yields the image data from the start, including the bytes already read.
+// Only the formats in allowedFormats are accepted, or any of imageFormats if it is nil,
+// with at most maxPixels pixels unless it is 0.
+func sniffImage(meta *ImageMeta, imageData io.Reader, allowedFormats map[string]bool, maxPixels int64) (io.Reader, error) {
+	var header bytes.Buffer
+	tee := io.TeeReader(imageData, &header)
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(tee, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+
+	mimeType := http.DetectContentType(head[:n])
+	formatName, format, ok := formatByMimeType(mimeType)
+	if !ok || (allowedFormats != nil && !allowedFormats[formatName]) {
+		return nil, fmt.Errorf("%w: content type %s is not allowed", ErrInvalidImage, mimeType)
+	}
+
+	config, decodedFormat, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(head[:n]), tee))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode %s header: %v", ErrInvalidImage, mimeType, err)
+	}
+	if decodedFormat != formatName {
+		return nil, fmt.Errorf("%w: content type %s does not match %s header", ErrInvalidImage, mimeType, decodedFormat)
+	}
+	if maxPixels > 0 && int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d is more than %d pixels", ErrInvalidImage, config.Width, config.Height, maxPixels)
+	}
+
+	if meta.Type != "" && !format.matches(meta.Type) {
+		return nil, fmt.Errorf("%w: %s content does not match the declared type %s", ErrInvalidImage, mimeType, meta.Type)
+	}
+
+	meta.MimeType = format.mimeType
+	meta.Width = config.Width
+	meta.Height = config.Height
+
+	return io.MultiReader(&header, imageData), nil
+}
+
+func formatByMimeType(mimeType string) (string, imageFormat, bool) {
+	for name, format := range imageFormats {
+		if format.mimeType == mimeType {
+			return name, format, true
+		}
+	}
+	return "", imageFormat{}, false
+}
+
+// matches accepts an extension with or without the dot, or the MIME type of the format
+func (format imageFormat) matches(imageType string) bool {
+	imageType = strings.ToLower(strings.TrimSpace(imageType))
+	if imageType == format.mimeType {
+		return true
+	}
+	if !strings.HasPrefix(imageType, ".") {
+		imageType = "." + imageType
+	}
+	for _, extension := range format.extensions {
+		if imageType == extension {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffImageFile detects the format and dimensions of an image file that is already stored,
+// files that are not an allowed image are left without them
+func sniffImageFile(path string) ImageMeta {
+	var meta ImageMeta
+
+	file, err := os.Open(path)
+	if err != nil {
+		return meta
+	}
+	defer file.Close()
+
+	_, err = sniffImage(&meta, file, nil, 0)
+	if err != nil {
+		slog.Warn("cannot detect the image format", "path", path, "error", err)
+	}
+	return meta
+}
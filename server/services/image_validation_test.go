@@ -0,0 +1,56 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniffImageMaxPixels(t *testing.T) {
+	data := encodePNG(t, 100, 50)
+
+	tests := []struct {
+		name      string
+		maxPixels int64
+		wantErr   bool
+	}{
+		{"no limit", 0, false},
+		{"at the limit", 5000, false},
+		{"over the limit", 4999, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var meta ImageMeta
+			imageData, err := sniffImage(&meta, bytes.NewReader(data), nil, tt.maxPixels)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidImage) {
+					t.Errorf("got %v, want ErrInvalidImage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sniffImage: %v", err)
+			}
+			if meta.Width != 100 || meta.Height != 50 || meta.MimeType != "image/png" {
+				t.Errorf("got %dx%d %s, want 100x50 image/png", meta.Width, meta.Height, meta.MimeType)
+			}
+			read, err := io.ReadAll(imageData)
+			if err != nil || !bytes.Equal(read, data) {
+				t.Errorf("returned reader does not yield the image data from the start: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const variantsFolderName = ".variants"
+
+var (
+	// ErrVariantNotFound is returned for variant names that are not configured
+	ErrVariantNotFound = errors.New("image variant not found")
+	// ErrUnsupportedImage is returned when the image cannot be decoded to generate a variant
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	// ErrImageTooLarge is returned when the image has too many pixels to be decoded for a variant
+	ErrImageTooLarge = errors.New("image is too large for variants")
+	// errImageReplaced is returned when the image was overwritten while its variant was generated
+	errImageReplaced = errors.New("image was replaced")
+)
+
+// OpenVariant returns the resized variant of the image, generating it first if it is missing
+func (store *DiskImageStore) OpenVariant(imageID string, variant string) (*ImageInfo, io.ReadSeekCloser, error) {
+	maxSide, ok := store.variants[variant]
+	if !ok {
+		return nil, nil, ErrVariantNotFound
+	}
+
+	store.mutex.RLock()
+	info, ok := store.images[imageID]
+	store.mutex.RUnlock()
+	if !ok || info.DeletedAt != nil {
+		return nil, nil, ErrImageNotFound
+	}
+
+	variantPath, err := store.findVariant(imageID, variant)
+	if errors.Is(err, os.ErrNotExist) {
+		// concurrent requests for the same missing variant share one generation
+		_, err, _ = store.variantGroup.Do(imageID+"/"+variant, func() (interface{}, error) {
+			return nil, store.generateVariant(context.Background(), info, variant, maxSide)
+		})
+		// the variant of the old content was dropped, the new content gets one of its own
+		if errors.Is(err, errImageReplaced) {
+			return store.OpenVariant(imageID, variant)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		variantPath, err = store.findVariant(imageID, variant)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ext := filepath.Ext(variantPath)
+	variantInfo := *info
+	variantInfo.Name = strings.TrimSuffix(info.Name, filepath.Ext(info.Name)) + "_" + variant + ext
+	variantInfo.Type = ext
+	variantInfo.Path = variantPath
+
+	return openImage(&variantInfo)
+}
+
+// GenerateVariants generates every configured variant of the image that is still missing.
+// It stops with the error of ctx once it ends, the image being decoded is abandoned too.
+func (store *DiskImageStore) GenerateVariants(ctx context.Context, imageID string) error {
+	store.mutex.RLock()
+	info, ok := store.images[imageID]
+	store.mutex.RUnlock()
+	if !ok {
+		return ErrImageNotFound
+	}
+
+	for variant, maxSide := range store.variants {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := store.findVariant(imageID, variant)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		_, err, _ = store.variantGroup.Do(imageID+"/"+variant, func() (interface{}, error) {
+			return nil, store.generateVariant(ctx, info, variant, maxSide)
+		})
+		if errors.Is(err, ErrImageTooLarge) {
+			slog.Info("skipped variants of large image", "image_id", imageID, "error", err)
+			return nil
+		}
+		// the save that replaced the image generates the variants of the new content
+		if errors.Is(err, errImageReplaced) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findVariant returns the path of the variant file, its extension depends on the original format
+func (store *DiskImageStore) findVariant(imageID string, variant string) (string, error) {
+	entries, err := os.ReadDir(store.variantFolder(imageID))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", os.ErrNotExist
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot look up image variant: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.TrimSuffix(name, filepath.Ext(name)) == variant {
+			return filepath.Join(store.variantFolder(imageID), name), nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func (store *DiskImageStore) generateVariant(ctx context.Context, info *ImageInfo, variant string, maxSide int) error {
+	file, err := os.Open(info.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("cannot open image file: %w", err)
+	}
+	defer file.Close()
+
+	// the decoded image takes a few bytes per pixel, its header tells how many there are
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if store.maxVariantPixels > 0 && pixels > store.maxVariantPixels {
+		return fmt.Errorf("%w: %dx%d is more than %d pixels", ErrImageTooLarge, config.Width, config.Height, store.maxVariantPixels)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("cannot read image file: %w", err)
+	}
+
+	// the decoders read the file as they go, so that reading it stops them once ctx ends
+	src, format, err := image.Decode(&contextReader{ctx: ctx, r: file})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	folder := store.variantFolder(info.ID)
+	err = os.MkdirAll(folder, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot create variant folder: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(folder, ".tmp-"+variant+"-*")
+	if err != nil {
+		return fmt.Errorf("cannot create variant file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	dst := resizeImage(src, maxSide)
+	if err := ctx.Err(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	// jpeg stays jpeg, everything else becomes a png so that gif variants keep their transparency
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+		err = jpeg.Encode(tmpFile, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(tmpFile, dst)
+	}
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("cannot encode image variant: %w", err)
+	}
+
+	err = tmpFile.Chmod(0644)
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("cannot change variant file mode: %w", err)
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("cannot close variant file: %w", err)
+	}
+
+	// Save drops the variants of an image it overwrites under the lock,
+	// the variant of the old content must not be moved into place after that
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	if current, ok := store.images[info.ID]; !ok || current.Checksum != info.Checksum {
+		return fmt.Errorf("cannot move variant file into place: %w", errImageReplaced)
+	}
+	err = os.Rename(tmpFile.Name(), filepath.Join(folder, variant+ext))
+	if err != nil {
+		return fmt.Errorf("cannot move variant file into place: %w", err)
+	}
+
+	slog.Info("generated variant", "image_id", info.ID, "variant", variant)
+	return nil
+}
+
+// removeVariants drops all the generated variants of the image
+func (store *DiskImageStore) removeVariants(imageID string) error {
+	err := os.RemoveAll(store.variantFolder(imageID))
+	if err != nil {
+		return fmt.Errorf("cannot remove image variants: %w", err)
+	}
+	return nil
+}
+
+// reconcileVariants removes the variants of images that are not in the index
+func (store *DiskImageStore) reconcileVariants() error {
+	fileInfos, err := ioutil.ReadDir(filepath.Join(store.imageFolder, variantsFolderName))
+	if err != nil {
+		return fmt.Errorf("cannot read variants dir: %w", err)
+	}
+
+	for _, fileInfo := range fileInfos {
+		if _, ok := store.images[fileInfo.Name()]; ok {
+			continue
+		}
+
+		slog.Warn("removing variants of unknown image", "image_id", fileInfo.Name())
+		err := store.removeVariants(fileInfo.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *DiskImageStore) variantFolder(imageID string) string {
+	return filepath.Join(store.imageFolder, variantsFolderName, imageID)
+}
+
+// resizeImage scales the image down so that its longer side fits maxSide.
+// Every destination pixel is the average of the source pixels it covers,
+// they are read from the source as they are, without converting the whole image first.
+func resizeImage(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	if srcWidth <= maxSide && srcHeight <= maxSide {
+		return src
+	}
+
+	dstWidth, dstHeight := maxSide, maxSide
+	if srcWidth > srcHeight {
+		dstHeight = maxInt(1, srcHeight*maxSide/srcWidth)
+	} else {
+		dstWidth = maxInt(1, srcWidth*maxSide/srcHeight)
+	}
+
+	// the images of the standard decoders return their pixels without allocating a color for each
+	at := func(x, y int) color.RGBA64 {
+		r, g, b, a := src.At(x, y).RGBA()
+		return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(a)}
+	}
+	if pixels, ok := src.(image.RGBA64Image); ok {
+		at = pixels.RGBA64At
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := y * srcHeight / dstHeight
+		y1 := maxInt(y0+1, (y+1)*srcHeight/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := x * srcWidth / dstWidth
+			x1 := maxInt(x0+1, (x+1)*srcWidth/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := at(bounds.Min.X+sx, bounds.Min.Y+sy)
+					r += uint64(pixel.R)
+					g += uint64(pixel.G)
+					b += uint64(pixel.B)
+					a += uint64(pixel.A)
+					n++
+				}
+			}
+
+			pixel := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4]
+			pixel[0] = uint8(r / n >> 8)
+			pixel[1] = uint8(g / n >> 8)
+			pixel[2] = uint8(b / n >> 8)
+			pixel[3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
+
+// contextReader fails the reads once its context ends
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (reader *contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.r.Read(p)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+)
+
+func TestResizeImageAveragesSourcePixels(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 14, 12))
+	for y := 10; y < 12; y++ {
+		src.SetNRGBA(10, y, color.NRGBA{R: 255, A: 255})
+		src.SetNRGBA(11, y, color.NRGBA{B: 255, A: 255})
+		src.SetNRGBA(12, y, color.NRGBA{G: 255, A: 255})
+		src.SetNRGBA(13, y, color.NRGBA{G: 255, A: 255})
+	}
+
+	dst := resizeImage(src, 2)
+	if dst.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("got bounds %v, want 2x1", dst.Bounds())
+	}
+	want := []color.RGBA{{R: 127, B: 127, A: 255}, {G: 255, A: 255}}
+	for x, wantColor := range want {
+		if got := color.RGBAModel.Convert(dst.At(x, 0)); got != wantColor {
+			t.Errorf("pixel %d: got %v, want %v", x, got, wantColor)
+		}
+	}
+
+	if small := resizeImage(src, 4); small != image.Image(src) {
+		t.Errorf("image that fits was copied")
+	}
+}
+
+func TestDiskImageStoreSkipsVariantsOfLargeImages(t *testing.T) {
+	variants := map[string]int{"thumbnail": 16}
+	data := encodePNG(t, 100, 50)
+
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, variants, 4999)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	info, err := store.Save(context.Background(), ImageMeta{Name: "a.png"}, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	err = store.GenerateVariants(context.Background(), info.ID)
+	if err != nil {
+		t.Errorf("GenerateVariants: %v, want large images skipped", err)
+	}
+	_, _, err = store.OpenVariant(info.ID, "thumbnail")
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("OpenVariant: got %v, want ErrImageTooLarge", err)
+	}
+
+	store.maxVariantPixels = 5000
+	variantInfo, variant, err := store.OpenVariant(info.ID, "thumbnail")
+	if err != nil {
+		t.Fatalf("OpenVariant: %v", err)
+	}
+	defer variant.Close()
+	config, _, err := image.DecodeConfig(variant)
+	if err != nil {
+		t.Fatalf("cannot decode variant %s: %v", variantInfo.Name, err)
+	}
+	if config.Width != 16 || config.Height != 8 {
+		t.Errorf("got variant of %dx%d, want 16x8", config.Width, config.Height)
+	}
+}
+
+func TestDiskImageStoreDropsVariantsOfReplacedContent(t *testing.T) {
+	store, err := NewDiskImageStore(t.TempDir(), time.Hour, map[string]int{"thumbnail": 16}, 0)
+	if err != nil {
+		t.Fatalf("NewDiskImageStore: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	info, err := store.Save(ctx, ImageMeta{Name: "a.png"}, bytes.NewReader(encodePNG(t, 100, 50)))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	_, err = store.Save(ctx, ImageMeta{Name: "a.png", ConflictPolicy: protos.ConflictPolicy_OVERWRITE}, bytes.NewReader(encodePNG(t, 50, 100)))
+	if err != nil {
+		t.Fatalf("Save with OVERWRITE: %v", err)
+	}
+
+	// a generation of the old content that finishes after the overwrite leaves nothing behind
+	err = store.generateVariant(context.Background(), info, "thumbnail", 16)
+	if !errors.Is(err, errImageReplaced) {
+		t.Errorf("generateVariant of the old content: got %v, want errImageReplaced", err)
+	}
+	err = store.GenerateVariants(context.Background(), info.ID)
+	if err != nil {
+		t.Fatalf("GenerateVariants: %v", err)
+	}
+
+	_, variant, err := store.OpenVariant(info.ID, "thumbnail")
+	if err != nil {
+		t.Fatalf("OpenVariant: %v", err)
+	}
+	defer variant.Close()
+	config, _, err := image.DecodeConfig(variant)
+	if err != nil {
+		t.Fatalf("cannot decode variant: %v", err)
+	}
+	if config.Width != 8 || config.Height != 16 {
+		t.Errorf("got variant of %dx%d, want 8x16 of the new content", config.Width, config.Height)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes the names of all the metrics of the image server
+const metricsNamespace = "image_server"
+
+// uploadMetrics record what uploads receive, they are only exported once RegisterMetrics is called
+type uploadMetrics struct {
+	receivedBytes prometheus.Counter
+	chunkSizes    prometheus.Histogram
+	imageSizes    prometheus.Histogram
+}
+
+func newUploadMetrics() uploadMetrics {
+	return uploadMetrics{
+		receivedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: metricsNamespace + "_upload_received_bytes_total",
+			Help: "Bytes received in upload chunks.",
+		}),
+		chunkSizes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    metricsNamespace + "_upload_chunk_size_bytes",
+			Help:    "Sizes of the upload chunks received.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}),
+		imageSizes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    metricsNamespace + "_upload_image_size_bytes",
+			Help:    "Sizes of the uploaded images that were saved.",
+			Buckets: prometheus.ExponentialBuckets(16<<10, 4, 9),
+		}),
+	}
+}
+
+func (m *uploadMetrics) observeChunk(size int) {
+	m.receivedBytes.Add(float64(size))
+	m.chunkSizes.Observe(float64(size))
+}
+
+func (m *uploadMetrics) observeImage(size int64) {
+	m.imageSizes.Observe(float64(size))
+}
+
+// RegisterMetrics registers the upload metrics and the state of the admission queues.
+// It must be called before the server starts serving.
+func (server *ImageServer) RegisterMetrics(registerer prometheus.Registerer) {
+	registerer.MustRegister(server.uploadMetrics.receivedBytes, server.uploadMetrics.chunkSizes, server.uploadMetrics.imageSizes)
+
+	for _, admission := range []*admission{server.readAdmission, server.uploadAdmission, server.downloadAdmission, server.uploadBytes} {
+		admission := admission
+		queue := prometheus.Labels{"queue": admission.name}
+		gauge := func(name string, help string, value func() float64) prometheus.Collector {
+			return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: metricsNamespace + name, Help: help, ConstLabels: queue}, value)
+		}
+		counter := func(name string, help string, value func() float64) prometheus.Collector {
+			return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: metricsNamespace + name, Help: help, ConstLabels: queue}, value)
+		}
+
+		registerer.MustRegister(
+			gauge("_admission_limit", "Slots of a request queue, or bytes of the upload budget.",
+				func() float64 { return float64(admission.limit) }),
+			gauge("_admission_in_use", "Slots or bytes held by the requests in flight.",
+				func() float64 { return float64(admission.running.Load()) }),
+			gauge("_admission_utilization", "Share of the limit that is in use.",
+				func() float64 { return float64(admission.running.Load()) / float64(admission.limit) }),
+			gauge("_admission_waiting", "Requests waiting for a slot or for bytes.",
+				func() float64 { return float64(admission.waiting.Load()) }),
+			counter("_admission_admitted_total", "Requests that got a slot or bytes.",
+				func() float64 { return float64(admission.admitted.Load()) }),
+			counter("_admission_rejected_total", "Requests turned away because nothing freed up in time.",
+				func() float64 { return float64(admission.rejected.Load()) }),
+			counter("_admission_wait_seconds_total", "Time requests spent waiting in the queue.",
+				func() float64 { return float64(admission.totalWait.Load()) / 1e9 }),
+		)
+	}
+}
+
+// RegisterMetrics registers the number and the size of the stored images, live and in the trash,
+// and of the blobs that identical images share
+func (store *DiskImageStore) RegisterMetrics(registerer prometheus.Registerer) {
+	gauge := func(name string, help string, labels prometheus.Labels, value func() float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: metricsNamespace + name, Help: help, ConstLabels: labels}, value)
+	}
+	live, deleted := prometheus.Labels{"state": "live"}, prometheus.Labels{"state": "deleted"}
+
+	registerer.MustRegister(
+		gauge("_store_images", "Images in the store.", live, func() float64 {
+			images, _, _, _ := store.stats()
+			return float64(images)
+		}),
+		gauge("_store_images", "Images in the store.", deleted, func() float64 {
+			_, images, _, _ := store.stats()
+			return float64(images)
+		}),
+		gauge("_store_bytes", "Bytes of the images in the store, variants left out.", live, func() float64 {
+			_, _, bytes, _ := store.stats()
+			return float64(bytes)
+		}),
+		gauge("_store_bytes", "Bytes of the images in the store, variants left out.", deleted, func() float64 {
+			_, _, _, bytes := store.stats()
+			return float64(bytes)
+		}),
+		gauge("_store_blobs", "Blobs the content of identical images is stored in once.", nil, func() float64 {
+			blobs, _ := store.blobStats()
+			return float64(blobs)
+		}),
+		gauge("_store_blob_bytes", "Bytes of the blobs, each counted once however many images share it.", nil, func() float64 {
+			_, blobBytes := store.blobStats()
+			return float64(blobBytes)
+		}),
+	)
+}
+
+// stats counts the images and their bytes, split into live ones and the ones in the trash
+func (store *DiskImageStore) stats() (int, int, int64, int64) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var live, deleted int
+	var liveBytes, deletedBytes int64
+	for _, info := range store.images {
+		if info.DeletedAt != nil {
+			deleted++
+			deletedBytes += info.Size
+		} else {
+			live++
+			liveBytes += info.Size
+		}
+	}
+	return live, deleted, liveBytes, deletedBytes
+}
+
+// blobStats counts the blobs and their bytes
+func (store *DiskImageStore) blobStats() (int, int64) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	blobs := make(map[string]bool, len(store.blobRefs))
+	var blobBytes int64
+	for _, info := range store.images {
+		if info.Blob != "" && !blobs[info.Blob] {
+			blobs[info.Blob] = true
+			blobBytes += info.Size
+		}
+	}
+	return len(blobs), blobBytes
+}
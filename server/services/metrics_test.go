@@ -0,0 +1,56 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navruz-rakhimov/tages-project/protos"
+	"github.com/navruz-rakhimov/tages-project/server/metrics"
+	"google.golang.org/grpc"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	server, store := newTestImageServer(t)
+
+	registry := metrics.NewRegistry()
+	server.RegisterMetrics(registry)
+	store.RegisterMetrics(registry)
+	rpcMetrics := metrics.NewRPCMetrics(registry)
+
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/imageservice.ImageService/GetImageInfoList"}
+	_, err := rpcMetrics.UnaryServerInterceptor()(ctx, &protos.GetImageInfoListRequest{}, info, func(ctx context.Context, req any) (any, error) {
+		return server.GetImageInfoList(ctx, req.(*protos.GetImageInfoListRequest))
+	})
+	if err != nil {
+		t.Fatalf("GetImageInfoList: %v", err)
+	}
+	_, err = server.receiveImage(ctx, ImageMeta{Name: "a.png"}, bytes.NewReader(encodePNG(t, 10, 10)))
+	if err != nil {
+		t.Fatalf("receiveImage: %v", err)
+	}
+	server.uploads.Wait()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler(registry).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(body)
+
+	for _, want := range []string{
+		`grpc_server_handled_total{grpc_code="OK",grpc_method="GetImageInfoList",grpc_service="imageservice.ImageService",grpc_type="unary"} 1`,
+		`image_server_store_images{state="live"} 1`,
+		`image_server_admission_limit{queue="read"} 10`,
+		`image_server_upload_image_size_bytes_count 1`,
+	} {
+		if !strings.Contains(output, want+"\n") {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/navruz-rakhimov/tages-project/protos"
+	"github.com/navruz-rakhimov/tages-project/server/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// maxFormValueSize is the largest form field of an upload besides the image
+const maxFormValueSize = 4 << 10
+
+// maxFormOverhead is how much larger than the image an upload request may be,
+// room for the other form fields, the part headers and the boundaries
+const maxFormOverhead = 64 << 10
+
+// restConflictPolicies are the values of the conflict_policy field of an upload
+var restConflictPolicies = map[string]ConflictPolicy{
+	"reject":    pb.ConflictPolicy_REJECT,
+	"overwrite": pb.ConflictPolicy_OVERWRITE,
+	"rename":    pb.ConflictPolicy_AUTO_RENAME,
+}
+
+// restGateway serves the images over HTTP with the limits, validation and store of the image server
+type restGateway struct {
+	server      *ImageServer
+	interceptor *auth.Interceptor
+}
+
+// NewRESTHandler returns the HTTP routes of the image server:
+//
+//	POST /images          uploads the "image" part of a multipart form, after the optional
+//	                      "name", "type" and "conflict_policy" (reject, overwrite, rename) fields
+//	GET /images           lists the images, with the fields of GetImageInfoListRequest as query parameters
+//	GET /images/{id}      downloads the image, with ETag and Range support
+//	DELETE /images/{id}   moves the image to the trash
+//
+// Responses and errors are JSON with the field names of the protos.
+// Requests need a bearer token one of the authenticators of the interceptor accepts, unless it is nil.
+func NewRESTHandler(server *ImageServer, interceptor *auth.Interceptor) http.Handler {
+	return &restGateway{server: server, interceptor: interceptor}
+}
+
+func (gateway *restGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if gateway.interceptor != nil {
+		ctx, err := gateway.interceptor.AuthenticateHTTP(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+
+	switch {
+	case r.URL.Path == "/images":
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			gateway.listImages(w, r)
+		case http.MethodPost:
+			gateway.uploadImage(w, r)
+		default:
+			methodNotAllowed(w, "GET, HEAD, POST")
+		}
+	case strings.HasPrefix(r.URL.Path, "/images/") && !strings.Contains(r.URL.Path[len("/images/"):], "/"):
+		imageID := r.URL.Path[len("/images/"):]
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			gateway.downloadImage(w, r, imageID)
+		case http.MethodDelete:
+			gateway.deleteImage(w, r, imageID)
+		default:
+			methodNotAllowed(w, "GET, HEAD, DELETE")
+		}
+	default:
+		writeError(w, status.Errorf(codes.NotFound, "cannot find route %s", r.URL.Path))
+	}
+}
+
+func (gateway *restGateway) listImages(w http.ResponseWriter, r *http.Request) {
+	req, err := listRequest(r.URL.Query())
+	if err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "cannot list images: %v", err))
+		return
+	}
+
+	res, err := gateway.server.GetImageInfoList(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeMessage(w, http.StatusOK, res)
+}
+
+// listRequest reads the fields of a list request from the query, the enums by name and the times in RFC 3339
+func listRequest(query url.Values) (*pb.GetImageInfoListRequest, error) {
+	req := &pb.GetImageInfoListRequest{
+		NamePattern: query.Get("name_pattern"),
+		ImageType:   query.Get("image_type"),
+		PageToken:   query.Get("page_token"),
+	}
+
+	var err error
+	if req.IncludeDeleted, err = queryBool(query, "include_deleted"); err != nil {
+		return nil, err
+	}
+	if req.AllOwners, err = queryBool(query, "all_owners"); err != nil {
+		return nil, err
+	}
+	if value := query.Get("page_size"); value != "" {
+		pageSize, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, errors.New("page_size: must be a number")
+		}
+		req.PageSize = int32(pageSize)
+	}
+	if value := query.Get("sort_order"); value != "" {
+		sortOrder, ok := pb.GetImageInfoListRequest_SortOrder_value[strings.ToUpper(value)]
+		if !ok {
+			return nil, errors.New("sort_order: unknown order " + strconv.Quote(value))
+		}
+		req.SortOrder = pb.GetImageInfoListRequest_SortOrder(sortOrder)
+	}
+	if req.CreatedAfter, err = queryTime(query, "created_after"); err != nil {
+		return nil, err
+	}
+	if req.CreatedBefore, err = queryTime(query, "created_before"); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func queryBool(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New(name + ": must be true or false")
+	}
+	return b, nil
+}
+
+func queryTime(query url.Values, name string) (*timestamppb.Timestamp, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New(name + ": must be an RFC 3339 time")
+	}
+	return timestamppb.New(t), nil
+}
+
+// uploadImage saves the image part of a multipart form. The part is streamed to the store
+// and holds the length of the body, at most the largest image size, of the upload budget while it does.
+func (gateway *restGateway) uploadImage(w http.ResponseWriter, r *http.Request) {
+	server := gateway.server
+	ctx := r.Context()
+
+	if err := server.beginUpload(); err != nil {
+		writeError(w, err)
+		return
+	}
+	defer server.uploads.Done()
+
+	if err := server.uploadAdmission.acquire(ctx); err != nil {
+		writeError(w, err)
+		return
+	}
+	defer server.uploadAdmission.release()
+
+	r.Body = http.MaxBytesReader(w, r.Body, server.maxImageSize+maxFormOverhead)
+	owner, _ := caller(ctx)
+	meta := ImageMeta{Owner: owner}
+	// the image part is smaller than the body, so the length of the body bounds the bytes it takes from the budget
+	if r.ContentLength > 0 && r.ContentLength < server.maxImageSize {
+		meta.Size = r.ContentLength
+	}
+	parts, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, status.Errorf(codes.InvalidArgument, "cannot read multipart form: %v", err))
+		return
+	}
+
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			writeError(w, status.Error(codes.InvalidArgument, "cannot upload image: image part is missing"))
+			return
+		}
+		if err != nil {
+			writeError(w, status.Errorf(codes.InvalidArgument, "cannot read multipart form: %v", err))
+			return
+		}
+
+		switch part.FormName() {
+		case "name":
+			meta.Name, err = formValue(part)
+		case "type":
+			meta.Type, err = formValue(part)
+		case "conflict_policy":
+			var value string
+			value, err = formValue(part)
+			policy, ok := restConflictPolicies[value]
+			if err == nil && !ok {
+				err = status.Errorf(codes.InvalidArgument, "cannot upload image: unknown conflict policy %q, known are reject, overwrite, rename", value)
+			}
+			meta.ConflictPolicy = policy
+		case "image":
+			// the name and type of the file are used for the fields that did not come before it
+			if meta.Name == "" {
+				meta.Name = filepath.Base(part.FileName())
+			}
+			if meta.Type == "" {
+				meta.Type = filepath.Ext(part.FileName())
+			}
+			gateway.saveImage(w, r, meta, limitedPart{part})
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+}
+
+func (gateway *restGateway) saveImage(w http.ResponseWriter, r *http.Request, meta ImageMeta, imageData io.Reader) {
+	info, err := gateway.server.receiveImage(r.Context(), meta, imageData)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/images/"+url.PathEscape(info.ID))
+	writeMessage(w, http.StatusCreated, &pb.UploadImageResponse{
+		Id:           info.ID,
+		ImageName:    info.Name,
+		Size:         uint32(info.Size),
+		Deduplicated: info.Deduplicated,
+	})
+}
+
+// limitedPart reports an image part that runs past the size limit of the request as a status error
+type limitedPart struct {
+	io.Reader
+}
+
+func (part limitedPart) Read(p []byte) (int, error) {
+	n, err := part.Reader.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		err = status.Errorf(codes.InvalidArgument, "cannot receive image data: request is larger than %d bytes", tooLarge.Limit)
+	}
+	return n, err
+}
+
+// formValue reads a form field that is not the image
+func formValue(part io.Reader) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize+1))
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "cannot read multipart form: %v", err)
+	}
+	if len(value) > maxFormValueSize {
+		return "", status.Errorf(codes.InvalidArgument, "cannot read multipart form: field is longer than %d bytes", maxFormValueSize)
+	}
+	return string(value), nil
+}
+
+// downloadImage serves the image with its MIME type, the checksum as ETag and the update time as Last-Modified.
+// Conditional and range requests are answered by http.ServeContent.
+func (gateway *restGateway) downloadImage(w http.ResponseWriter, r *http.Request, imageID string) {
+	server := gateway.server
+	if err := server.downloadAdmission.acquire(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	defer server.downloadAdmission.release()
+
+	info, image, err := server.openImage(r.Context(), imageID, "")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer image.Close()
+
+	// without a MIME type ServeContent sniffs the content
+	if info.MimeType != "" {
+		w.Header().Set("Content-Type", info.MimeType)
+	}
+	if info.Checksum != "" {
+		w.Header().Set("ETag", strconv.Quote(info.Checksum))
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": info.Name}))
+	http.ServeContent(w, r, info.Name, info.UpdatedAt, image)
+}
+
+func (gateway *restGateway) deleteImage(w http.ResponseWriter, r *http.Request, imageID string) {
+	_, err := gateway.server.DeleteImage(r.Context(), &pb.DeleteImageRequest{Id: imageID})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeMessage(w http.ResponseWriter, code int, message proto.Message) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		writeError(w, status.Errorf(codes.Internal, "cannot encode response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeStatus(w, http.StatusMethodNotAllowed, status.New(codes.Unimplemented, "method is not allowed, allowed are "+allowed))
+}
+
+// writeError writes the status of err as JSON with the HTTP status code that matches its code.
+// The retry delay of exhausted admissions is sent as a Retry-After header too.
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(retryInfo.GetRetryDelay().AsDuration()/time.Second), 10))
+		}
+	}
+	writeStatus(w, httpStatus(st.Code()), st)
+}
+
+func writeStatus(w http.ResponseWriter, code int, st *status.Status) {
+	data, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		http.Error(w, st.Message(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// httpStatus maps a status code to the HTTP status code with the same meaning
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		// the status nginx uses for a client that went away
+		return 499
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+)
+
+// formField is a field of a multipart upload, the image part if fileName is set
+type formField struct {
+	name     string
+	fileName string
+	data     []byte
+}
+
+func postForm(t *testing.T, url string, fields ...formField) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, field := range fields {
+		var part io.Writer
+		var err error
+		if field.fileName != "" {
+			part, err = form.CreateFormFile(field.name, field.fileName)
+		} else {
+			part, err = form.CreateFormField(field.name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(field.data)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(url+"/images", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST /images: %v", err)
+	}
+	return res
+}
+
+func doRequest(t *testing.T, method string, url string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, body
+}
+
+func TestRESTUploadAndDownload(t *testing.T) {
+	server, _ := newTestImageServer(t)
+	gateway := httptest.NewServer(NewRESTHandler(server, nil))
+	defer gateway.Close()
+	data := encodePNG(t, 20, 10)
+
+	res := postForm(t, gateway.URL,
+		formField{name: "name", data: []byte("a.png")},
+		formField{name: "image", fileName: "upload.png", data: data},
+	)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(res.Body)
+		t.Fatalf("upload: got %d %s, want 201", res.StatusCode, body)
+	}
+	var uploaded struct {
+		ID        string `json:"id"`
+		ImageName string `json:"image_name"`
+		Size      int    `json:"size"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("cannot decode upload response: %v", err)
+	}
+	if uploaded.ImageName != "a.png" || uploaded.Size != len(data) {
+		t.Errorf("uploaded %q of %d bytes, want a.png of %d", uploaded.ImageName, uploaded.Size, len(data))
+	}
+	imageURL := gateway.URL + "/images/" + uploaded.ID
+	if location := res.Header.Get("Location"); location != "/images/"+uploaded.ID {
+		t.Errorf("Location is %q", location)
+	}
+
+	res, body := doRequest(t, http.MethodGet, imageURL, nil)
+	if res.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("download: got %d with %d bytes, want 200 with the image", res.StatusCode, len(body))
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != "image/png" {
+		t.Errorf("Content-Type is %q, want image/png", contentType)
+	}
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("download has no ETag")
+	}
+
+	res, body = doRequest(t, http.MethodGet, imageURL, http.Header{"If-None-Match": {etag}})
+	if res.StatusCode != http.StatusNotModified || len(body) != 0 {
+		t.Errorf("download with the ETag: got %d with %d bytes, want 304", res.StatusCode, len(body))
+	}
+	res, _ = doRequest(t, http.MethodGet, imageURL, http.Header{"If-None-Match": {`"other"`}})
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("download with another ETag: got %d, want 200", res.StatusCode)
+	}
+
+	res, body = doRequest(t, http.MethodGet, imageURL, http.Header{"Range": {"bytes=8-15"}})
+	if res.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[8:16]) {
+		t.Errorf("range download: got %d with %q, want 206 with bytes 8-15", res.StatusCode, body)
+	}
+	if contentRange := res.Header.Get("Content-Range"); contentRange == "" || !strings.HasPrefix(contentRange, "bytes 8-15/") {
+		t.Errorf("Content-Range is %q", contentRange)
+	}
+	res, _ = doRequest(t, http.MethodGet, imageURL, http.Header{"Range": {"bytes=100000-"}})
+	if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("range past the end: got %d, want 416", res.StatusCode)
+	}
+
+	res, body = doRequest(t, http.MethodGet, gateway.URL+"/images?name_pattern=a", nil)
+	if res.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(uploaded.ID)) {
+		t.Errorf("list: got %d %s, want the image", res.StatusCode, body)
+	}
+
+	res, _ = doRequest(t, http.MethodDelete, imageURL, nil)
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: got %d, want 204", res.StatusCode)
+	}
+	res, _ = doRequest(t, http.MethodGet, imageURL, nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("download of a deleted image: got %d, want 404", res.StatusCode)
+	}
+}
+
+func TestRESTUploadErrors(t *testing.T) {
+	server, _ := newTestImageServer(t)
+	gateway := httptest.NewServer(NewRESTHandler(server, nil))
+	defer gateway.Close()
+	data := encodePNG(t, 20, 10)
+
+	res := postForm(t, gateway.URL, formField{name: "image", fileName: "taken.png", data: data})
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("upload: got %d, want 201", res.StatusCode)
+	}
+
+	tests := []struct {
+		name     string
+		fields   []formField
+		wantCode int
+		wantText string
+	}{
+		{"without image", []formField{{name: "name", data: []byte("a.png")}}, http.StatusBadRequest, "image part is missing"},
+		{"unknown conflict policy", []formField{{name: "conflict_policy", data: []byte("merge")}}, http.StatusBadRequest, "unknown conflict policy"},
+		{"not an image", []formField{{name: "image", fileName: "a.png", data: []byte("text")}}, http.StatusBadRequest, "cannot accept image"},
+		{"name taken", []formField{{name: "image", fileName: "taken.png", data: data}}, http.StatusConflict, "already exists"},
+		{
+			"image too large",
+			[]formField{{name: "image", fileName: "a.png", data: append(append([]byte{}, data...), make([]byte, server.maxImageSize)...)}},
+			http.StatusBadRequest,
+			"too large",
+		},
+		// the fields that are not read count against the limit of the body too
+		{
+			"body too large",
+			[]formField{{name: "padding", data: make([]byte, server.maxImageSize+maxFormOverhead)}, {name: "image", fileName: "a.png", data: data}},
+			http.StatusBadRequest,
+			"too large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := postForm(t, gateway.URL, tt.fields...)
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			if res.StatusCode != tt.wantCode || !bytes.Contains(body, []byte(tt.wantText)) {
+				t.Errorf("got %d %s, want %d with %q", res.StatusCode, body, tt.wantCode, tt.wantText)
+			}
+		})
+	}
+	if running := server.uploadBytes.running.Load(); running != 0 {
+		t.Errorf("budget holds %d bytes after the uploads", running)
+	}
+}
+
+func TestRESTRoutes(t *testing.T) {
+	server, _ := newTestImageServer(t)
+	gateway := httptest.NewServer(NewRESTHandler(server, nil))
+	defer gateway.Close()
+
+	tests := []struct {
+		method    string
+		path      string
+		wantCode  int
+		wantAllow string
+	}{
+		{http.MethodPut, "/images", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{http.MethodPost, "/images/id", http.StatusMethodNotAllowed, "GET, HEAD, DELETE"},
+		{http.MethodGet, "/images/id/variant", http.StatusNotFound, ""},
+		{http.MethodGet, "/other", http.StatusNotFound, ""},
+		{http.MethodGet, "/images?sort_order=sideways", http.StatusBadRequest, ""},
+		{http.MethodGet, "/images?page_token=garbage", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res, _ := doRequest(t, tt.method, gateway.URL+tt.path, nil)
+			if res.StatusCode != tt.wantCode {
+				t.Errorf("got %d, want %d", res.StatusCode, tt.wantCode)
+			}
+			if allow := res.Header.Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow is %q, want %q", allow, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.Aborted, http.StatusConflict},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Canceled, 499},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unknown, http.StatusInternalServerError},
+		{codes.DataLoss, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := httpStatus(tt.code); got != tt.want {
+			t.Errorf("httpStatus(%s) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
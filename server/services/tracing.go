@@ -0,0 +1,19 @@
+package services
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans within a call, the spans of the calls themselves come from the gRPC interceptors
+var tracer = otel.Tracer("github.com/navruz-rakhimov/tages-project/server/services")
+
+// endSpan marks the span as failed if err is set and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
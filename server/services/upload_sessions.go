@@ -0,0 +1,347 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// savedUploadRetention is how long the image id of a finished upload can be looked up by its upload id
+const savedUploadRetention = 10 * time.Minute
+
+var (
+	// ErrUploadNotFound is returned for upload ids that are unknown or already garbage-collected
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadBusy is returned when another stream is still writing to the upload
+	ErrUploadBusy = errors.New("upload is in use by another stream")
+	// ErrUploadOffset is returned when a chunk does not continue at the committed size
+	ErrUploadOffset = errors.New("chunk offset does not match the committed size")
+	// ErrUploadOverflow is returned when chunks go past the size declared for the upload
+	ErrUploadOverflow = errors.New("chunk goes past the declared upload size")
+	// ErrTooManyUploads is returned when the owner or the server already has as many uploads as it may
+	ErrTooManyUploads = errors.New("too many uploads in progress")
+)
+
+// UploadSession is a resumable upload whose received bytes are kept in a file
+type UploadSession struct {
+	ID        string
+	ImageName string
+	ImageType string
+	Size      int64
+	// ConflictPolicy is applied when the finished upload is saved
+	ConflictPolicy ConflictPolicy
+	// Owner is the only caller that can see and continue the upload
+	Owner string
+
+	path       string
+	file       *os.File
+	committed  atomic.Int64
+	lastActive time.Time
+	busy       bool
+}
+
+// Committed returns the number of bytes received so far
+func (session *UploadSession) Committed() int64 {
+	return session.committed.Load()
+}
+
+// Complete reports whether all the declared bytes have been received
+func (session *UploadSession) Complete() bool {
+	return session.committed.Load() == session.Size
+}
+
+// Write appends the chunk that starts at the given offset to the upload
+func (session *UploadSession) Write(offset int64, data []byte) error {
+	committed := session.committed.Load()
+	if offset != committed {
+		return fmt.Errorf("%w: got %d, committed %d", ErrUploadOffset, offset, committed)
+	}
+	if committed+int64(len(data)) > session.Size {
+		return fmt.Errorf("%w: %d > %d", ErrUploadOverflow, committed+int64(len(data)), session.Size)
+	}
+
+	n, err := session.file.Write(data)
+	session.committed.Add(int64(n))
+	if err != nil {
+		return fmt.Errorf("cannot write chunk to upload file: %w", err)
+	}
+	return nil
+}
+
+// UploadStatus is how far an upload got, ImageID is set once it is complete and its image is saved
+type UploadStatus struct {
+	Committed int64
+	Size      int64
+	ImageID   string
+}
+
+// savedUpload is what is kept of an upload after its image is saved
+type savedUpload struct {
+	owner   string
+	size    int64
+	imageID string
+	savedAt time.Time
+}
+
+// UploadSessions keeps track of the resumable uploads and removes the abandoned ones
+type UploadSessions struct {
+	mutex    sync.Mutex
+	folder   string
+	timeout  time.Duration
+	sessions map[string]*UploadSession
+	saved    map[string]savedUpload
+	// every session holds an open file, so there are at most maxSessions, maxOwnerSessions of each owner
+	maxSessions      int
+	maxOwnerSessions int
+	stopGC           chan struct{}
+	gcDone           chan struct{}
+}
+
+// NewUploadSessions keeps upload data in the given folder, sessions without activity
+// for longer than timeout are garbage-collected. At most maxSessions are kept at once,
+// and maxOwnerSessions of every owner.
+func NewUploadSessions(folder string, timeout time.Duration, maxSessions int, maxOwnerSessions int) (*UploadSessions, error) {
+	// sessions live in memory only, so files of a previous run cannot be resumed
+	err := os.RemoveAll(folder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot clean up upload folder: %w", err)
+	}
+	err = os.MkdirAll(folder, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload folder: %w", err)
+	}
+
+	sessions := &UploadSessions{
+		folder:           folder,
+		timeout:          timeout,
+		sessions:         make(map[string]*UploadSession),
+		saved:            make(map[string]savedUpload),
+		maxSessions:      maxSessions,
+		maxOwnerSessions: maxOwnerSessions,
+		stopGC:           make(chan struct{}),
+		gcDone:           make(chan struct{}),
+	}
+	go sessions.runGC()
+
+	return sessions, nil
+}
+
+// Start creates a new upload session for an image of the given size,
+// it returns ErrTooManyUploads when the sessions or the ones of the owner are at their limit
+func (sessions *UploadSessions) Start(meta ImageMeta, size int64) (*UploadSession, error) {
+	if err := sessions.checkLimits(meta.Owner); err != nil {
+		return nil, err
+	}
+
+	uploadID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate upload id: %w", err)
+	}
+
+	path := filepath.Join(sessions.folder, uploadID.String())
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload file: %w", err)
+	}
+
+	session := &UploadSession{
+		ID:             uploadID.String(),
+		ImageName:      meta.Name,
+		ImageType:      meta.Type,
+		Size:           size,
+		ConflictPolicy: meta.ConflictPolicy,
+		Owner:          meta.Owner,
+		path:           path,
+		file:           file,
+		lastActive:     time.Now(),
+	}
+
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	// the limits are checked again, the file was created without the lock
+	if err := sessions.checkLimitsLocked(meta.Owner); err != nil {
+		session.removeFile()
+		return nil, err
+	}
+	sessions.sessions[session.ID] = session
+	return session, nil
+}
+
+func (sessions *UploadSessions) checkLimits(owner string) error {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	return sessions.checkLimitsLocked(owner)
+}
+
+// checkLimitsLocked returns ErrTooManyUploads if another session would go over a limit,
+// the caller must hold the lock
+func (sessions *UploadSessions) checkLimitsLocked(owner string) error {
+	if len(sessions.sessions) >= sessions.maxSessions {
+		return fmt.Errorf("%w: the server has %d", ErrTooManyUploads, len(sessions.sessions))
+	}
+	owned := 0
+	for _, session := range sessions.sessions {
+		if session.Owner == owner {
+			owned++
+		}
+	}
+	if owned >= sessions.maxOwnerSessions {
+		return fmt.Errorf("%w: the owner has %d", ErrTooManyUploads, owned)
+	}
+	return nil
+}
+
+// Status returns how far the upload got, uploads of other owners are not found.
+// Uploads whose image is saved are found for savedUploadRetention.
+func (sessions *UploadSessions) Status(uploadID string, owner string) (UploadStatus, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	if saved, ok := sessions.saved[uploadID]; ok && saved.owner == owner && time.Since(saved.savedAt) < savedUploadRetention {
+		return UploadStatus{Committed: saved.size, Size: saved.size, ImageID: saved.imageID}, nil
+	}
+	session, ok := sessions.sessions[uploadID]
+	if !ok || session.Owner != owner {
+		return UploadStatus{}, ErrUploadNotFound
+	}
+	return UploadStatus{Committed: session.committed.Load(), Size: session.Size}, nil
+}
+
+// Acquire gives the stream exclusive access to the upload until it calls Release,
+// uploads of other owners are not found
+func (sessions *UploadSessions) Acquire(uploadID string, owner string) (*UploadSession, error) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	session, ok := sessions.sessions[uploadID]
+	if !ok || session.Owner != owner {
+		return nil, ErrUploadNotFound
+	}
+	if session.busy {
+		return nil, ErrUploadBusy
+	}
+
+	session.busy = true
+	session.lastActive = time.Now()
+	return session, nil
+}
+
+// Release gives up access to the upload so that it can be resumed by another stream
+func (sessions *UploadSessions) Release(session *UploadSession) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	session.busy = false
+	session.lastActive = time.Now()
+}
+
+// Open returns the received data of the upload from the start
+func (sessions *UploadSessions) Open(session *UploadSession) (*os.File, error) {
+	file, err := os.Open(session.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open upload file: %w", err)
+	}
+	return file, nil
+}
+
+// Saved drops the upload together with its data, but keeps the id of the image it was saved as
+// for savedUploadRetention
+func (sessions *UploadSessions) Saved(session *UploadSession, imageID string) {
+	sessions.mutex.Lock()
+	delete(sessions.sessions, session.ID)
+	sessions.saved[session.ID] = savedUpload{
+		owner:   session.Owner,
+		size:    session.Size,
+		imageID: imageID,
+		savedAt: time.Now(),
+	}
+	sessions.mutex.Unlock()
+
+	session.removeFile()
+}
+
+// Remove drops the upload together with its data
+func (sessions *UploadSessions) Remove(session *UploadSession) {
+	sessions.mutex.Lock()
+	delete(sessions.sessions, session.ID)
+	sessions.mutex.Unlock()
+
+	session.removeFile()
+}
+
+func (session *UploadSession) removeFile() {
+	session.file.Close()
+	if err := os.Remove(session.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("cannot remove upload file", "path", session.path, "error", err)
+	}
+}
+
+// Close stops the garbage collector and removes all the uploads
+func (sessions *UploadSessions) Close() error {
+	close(sessions.stopGC)
+	<-sessions.gcDone
+
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	for _, session := range sessions.sessions {
+		session.file.Close()
+	}
+	sessions.sessions = make(map[string]*UploadSession)
+	sessions.saved = make(map[string]savedUpload)
+
+	return os.RemoveAll(sessions.folder)
+}
+
+func (sessions *UploadSessions) runGC() {
+	defer close(sessions.gcDone)
+
+	interval := sessions.timeout / 2
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sessions.stopGC:
+			return
+		case <-ticker.C:
+			for _, session := range sessions.removeExpired(time.Now().Add(-sessions.timeout)) {
+				slog.Info("removed abandoned upload", "upload_id", session.ID, "image_name", session.ImageName)
+				session.removeFile()
+			}
+		}
+	}
+}
+
+// removeExpired drops the idle uploads from the sessions and returns them for cleaning up,
+// along with the saved uploads that are kept long enough
+func (sessions *UploadSessions) removeExpired(inactiveSince time.Time) []*UploadSession {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	for uploadID, saved := range sessions.saved {
+		if time.Since(saved.savedAt) >= savedUploadRetention {
+			delete(sessions.saved, uploadID)
+		}
+	}
+
+	var expired []*UploadSession
+	for uploadID, session := range sessions.sessions {
+		if !session.busy && session.lastActive.Before(inactiveSince) {
+			delete(sessions.sessions, uploadID)
+			expired = append(expired, session)
+		}
+	}
+	return expired
+}
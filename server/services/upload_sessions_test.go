@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadSessionsLimits(t *testing.T) {
+	sessions, err := NewUploadSessions(filepath.Join(t.TempDir(), "uploads"), time.Hour, 3, 2)
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+	defer sessions.Close()
+
+	alice, err := sessions.Start(ImageMeta{Owner: "alice"}, 10)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := sessions.Start(ImageMeta{Owner: "alice"}, 10); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := sessions.Start(ImageMeta{Owner: "alice"}, 10); !errors.Is(err, ErrTooManyUploads) {
+		t.Errorf("Start past the owner limit: got %v, want ErrTooManyUploads", err)
+	}
+	if _, err := sessions.Start(ImageMeta{Owner: "bob"}, 10); err != nil {
+		t.Fatalf("Start of another owner: %v", err)
+	}
+	if _, err := sessions.Start(ImageMeta{Owner: "carol"}, 10); !errors.Is(err, ErrTooManyUploads) {
+		t.Errorf("Start past the server limit: got %v, want ErrTooManyUploads", err)
+	}
+
+	sessions.Remove(alice)
+	if _, err := sessions.Start(ImageMeta{Owner: "carol"}, 10); err != nil {
+		t.Errorf("Start after Remove: %v", err)
+	}
+}
+
+func TestUploadSessionsStatusAfterSave(t *testing.T) {
+	sessions, err := NewUploadSessions(filepath.Join(t.TempDir(), "uploads"), time.Hour, 10, 10)
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+	defer sessions.Close()
+
+	session, err := sessions.Start(ImageMeta{Owner: "alice"}, 4)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := session.Write(0, []byte("data")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	sessions.Saved(session, "image-id")
+
+	uploadStatus, err := sessions.Status(session.ID, "alice")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if uploadStatus != (UploadStatus{Committed: 4, Size: 4, ImageID: "image-id"}) {
+		t.Errorf("got %+v", uploadStatus)
+	}
+	if _, err := sessions.Status(session.ID, "bob"); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Status of another owner: got %v, want ErrUploadNotFound", err)
+	}
+	if _, err := sessions.Acquire(session.ID, "alice"); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Acquire of a saved upload: got %v, want ErrUploadNotFound", err)
+	}
+
+	// saved uploads do not count against the limits and are dropped after a while
+	sessions.mutex.Lock()
+	saved := sessions.saved[session.ID]
+	saved.savedAt = time.Now().Add(-savedUploadRetention)
+	sessions.saved[session.ID] = saved
+	sessions.mutex.Unlock()
+	if _, err := sessions.Status(session.ID, "alice"); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Status after the retention: got %v, want ErrUploadNotFound", err)
+	}
+	sessions.removeExpired(time.Now().Add(-time.Hour))
+	if len(sessions.saved) != 0 {
+		t.Errorf("%d saved uploads are kept after the retention", len(sessions.saved))
+	}
+}
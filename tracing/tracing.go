@@ -0,0 +1,106 @@
+// Package tracing sets up OpenTelemetry tracing for the client and the server.
+// Spans are exported to an OTLP collector or written as JSON to standard output or a file,
+// the trace context travels between them in the gRPC metadata.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// the exporters spans can be sent to
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Exporters returns the names of the exporters spans can be sent to
+func Exporters() []string {
+	return []string{ExporterNone, ExporterStdout, ExporterOTLP}
+}
+
+// Options select where the spans are exported to
+type Options struct {
+	// Exporter is one of Exporters, nothing is recorded with ExporterNone
+	Exporter string
+	// File is appended to by the stdout exporter, standard output if empty
+	File string
+	// OTLPEndpoint is the host:port of the collector the otlp exporter sends to
+	OTLPEndpoint string
+	// OTLPInsecure sends the spans to the collector without TLS
+	OTLPInsecure bool
+}
+
+// Setup installs the global tracer provider of the service and the W3C trace context propagator.
+// The returned function flushes the spans that have not been exported yet and must be called before exiting.
+func Setup(ctx context.Context, serviceName string, opts Options) (func(context.Context) error, error) {
+	// incoming trace context is passed on even if nothing is recorded here
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		var w io.Writer = os.Stdout
+		if opts.File != "" {
+			var err error
+			file, err = os.OpenFile(opts.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, fmt.Errorf("cannot open trace file: %w", err)
+			}
+			w = file
+		}
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create stdout exporter: %w", err)
+		}
+		exporter = stdoutExporter
+	case ExporterOTLP:
+		otlpOptions := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.OTLPEndpoint)}
+		if opts.OTLPInsecure {
+			otlpOptions = append(otlpOptions, otlptracegrpc.WithInsecure())
+		}
+		// the exporter connects in the background, an unreachable collector does not stop the service
+		otlpExporter, err := otlptracegrpc.New(ctx, otlpOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create otlp exporter: %w", err)
+		}
+		exporter = otlpExporter
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot describe service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}